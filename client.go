@@ -1,11 +1,27 @@
 package gomcp
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
 // Client 定义了 MCP 客户端的接口
 type Client interface {
 	// SendRequest 发送 MCP 请求
 	SendRequest(method string, params map[string]interface{}) error
 	// ReceiveResponse 接收 MCP 响应
 	ReceiveResponse() (map[string]interface{}, error)
+	// Call 发送请求并等待对应的响应，返回响应中的 result；服务器返回错误时 err 为 *Error
+	Call(ctx context.Context, method string, params map[string]interface{}) (json.RawMessage, error)
+	// Notify 向服务器发送一个通知
+	Notify(method string, params map[string]interface{}) error
 	// Close 关闭客户端连接
 	Close() error
 }
@@ -17,3 +33,496 @@ type Request struct {
 	Params  map[string]interface{} `json:"params,omitempty"`
 	ID      int                    `json:"id"`
 }
+
+// Invoker 真正发送请求的函数。通过 Call 发出的请求会等待响应并返回 result，
+// 通过 SendRequest 发出的请求在写入成功后立即返回
+type Invoker func(ctx context.Context, request *Request) (json.RawMessage, error)
+
+// Interceptor 拦截客户端发出的请求，可以修改 request 后调用 invoker，
+// 也可以记录流量，或者多次调用 invoker 实现重试。请求 id 由 invoker 在每次发送时分配
+type Interceptor func(ctx context.Context, request *Request, invoker Invoker) (json.RawMessage, error)
+
+// MessageInterceptor 观察客户端收到的每一条消息（响应与通知），在消息分发之前调用
+type MessageInterceptor func(msg map[string]interface{})
+
+// NotificationHandler 处理服务器发来的通知。通知按到达顺序在独立的 goroutine 中依次处理，
+// 处理器中可以调用客户端的方法并等待响应，等待期间到达的通知会排队，长时间阻塞会延迟后续通知的处理
+type NotificationHandler func(method string, params map[string]interface{})
+
+// ClientOption 客户端的可选配置
+type ClientOption func(*clientOptions)
+
+// clientOptions 保存客户端的可选配置，零值可直接使用
+type clientOptions struct {
+	interceptors         []Interceptor
+	messageInterceptors  []MessageInterceptor
+	notificationHandlers map[string][]NotificationHandler
+	requestHandlers      map[string]RequestHandler
+	roots                *Roots
+	logger               *slog.Logger
+	httpClient           *http.Client
+	heartbeat            heartbeatOptions
+	clientInfo           Implementation
+	requestTimeout       time.Duration
+}
+
+// WithInterceptor 注册请求拦截器，先注册的拦截器位于外层
+func WithInterceptor(interceptors ...Interceptor) ClientOption {
+	return func(o *clientOptions) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+// WithMessageInterceptor 注册消息拦截器，按注册顺序依次调用
+func WithMessageInterceptor(interceptors ...MessageInterceptor) ClientOption {
+	return func(o *clientOptions) {
+		o.messageInterceptors = append(o.messageInterceptors, interceptors...)
+	}
+}
+
+// WithNotificationHandler 注册处理指定方法通知的回调，同一方法可以注册多个回调
+func WithNotificationHandler(method string, handler NotificationHandler) ClientOption {
+	return func(o *clientOptions) {
+		if o.notificationHandlers == nil {
+			o.notificationHandlers = make(map[string][]NotificationHandler)
+		}
+		o.notificationHandlers[method] = append(o.notificationHandlers[method], handler)
+	}
+}
+
+// WithRequestHandler 注册处理服务器发来的请求的处理器，例如 sampling/createMessage。
+// 请求在独立的 goroutine 中处理，处理器返回 *Error 时错误码会原样返回给服务器
+func WithRequestHandler(method string, handler RequestHandler) ClientOption {
+	return func(o *clientOptions) {
+		if o.requestHandlers == nil {
+			o.requestHandlers = make(map[string]RequestHandler)
+		}
+		o.requestHandlers[method] = handler
+	}
+}
+
+// WithRequestTimeout 设置请求的默认超时时间，ctx 的截止时间更早时以 ctx 为准
+func WithRequestTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.requestTimeout = timeout
+	}
+}
+
+// WithClientLogger 设置客户端使用的 logger，未设置时使用 slog.Default()。
+// 请求以 Debug 级别记录，失败的请求与无法解析的消息以 Warn 级别记录
+func WithClientLogger(logger *slog.Logger) ClientOption {
+	return func(o *clientOptions) {
+		o.logger = logger
+	}
+}
+
+// WithHTTPClient 设置 Streamable HTTP 与 HTTP+SSE 传输发送请求使用的 *http.Client，
+// 未设置时使用 http.DefaultClient
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(o *clientOptions) {
+		o.httpClient = client
+	}
+}
+
+// log 返回客户端使用的 logger
+func (o *clientOptions) log() *slog.Logger {
+	if o.logger == nil {
+		return slog.Default()
+	}
+	return o.logger
+}
+
+// http 返回 HTTP 传输使用的 *http.Client
+func (o *clientOptions) http() *http.Client {
+	if o.httpClient == nil {
+		return http.DefaultClient
+	}
+	return o.httpClient
+}
+
+func newClientOptions(opts []ClientOption) clientOptions {
+	var o clientOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// clientCore 包含各传输层客户端共享的请求发送、响应关联与拦截器逻辑，
+// 零值在调用 start 之后可直接使用
+type clientCore struct {
+	opts      clientOptions
+	transport transport
+	startOnce sync.Once
+	calls     pendingCalls
+	ctx       context.Context // 连接关闭时取消，用于处理服务器发来的请求
+	cancel    context.CancelFunc
+
+	mu       sync.Mutex // 保护下面的字段
+	queue    []map[string]interface{}
+	queued   chan struct{}
+	closed   chan struct{}
+	closeErr error
+	lostErr  error // 主动断开连接的原因，例如心跳超时
+
+	// 通知队列没有上限，读取循环不会因为处理器阻塞而停止读取响应
+	notifications     []*message
+	notificationsDone bool // 连接已关闭，处理完队列中的通知后退出
+	notified          chan struct{}
+}
+
+// start 绑定底层消息通道并启动读取循环，只有第一次调用生效
+func (c *clientCore) start(t transport) {
+	c.startOnce.Do(func() {
+		c.transport = t
+		c.ctx, c.cancel = context.WithCancel(context.Background())
+		c.queued = make(chan struct{}, 1)
+		c.closed = make(chan struct{})
+		c.notified = make(chan struct{}, 1)
+		if c.opts.roots != nil {
+			c.opts.roots.subscribe(c)
+		}
+		go safe(c.opts.log(), c.readMessages)()
+		go safe(c.opts.log(), c.handleNotifications)()
+		if c.opts.heartbeat.interval > 0 {
+			go safe(c.opts.log(), c.keepalive)()
+		}
+	})
+}
+
+// close 关闭底层消息通道
+func (c *clientCore) close() error {
+	return c.transport.close()
+}
+
+// keepalive 定期 ping 服务器，服务器连续多次未响应时关闭连接
+func (c *clientCore) keepalive() {
+	ping := func(ctx context.Context) error {
+		_, err := c.roundTrip(ctx, &Request{JsonRPC: "2.0", Method: "ping"})
+		return err
+	}
+	if err := c.opts.heartbeat.run(c.closed, ping, c.opts.log()); err != nil {
+		c.mu.Lock()
+		c.lostErr = err
+		c.mu.Unlock()
+		_ = c.close()
+	}
+}
+
+// call 经过拦截器链发送请求并等待响应
+func (c *clientCore) call(ctx context.Context, method string, params map[string]interface{}) (json.RawMessage, error) {
+	if c.opts.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.requestTimeout)
+		defer cancel()
+	}
+	request := &Request{
+		JsonRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	}
+	return c.intercept(ctx, request, c.roundTrip)
+}
+
+// send 经过拦截器链发送请求，不等待响应，响应通过 receive 获取
+func (c *clientCore) send(method string, params map[string]interface{}) error {
+	request := &Request{
+		JsonRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	}
+	_, err := c.intercept(context.Background(), request, func(ctx context.Context, request *Request) (json.RawMessage, error) {
+		request.ID = c.calls.nextID()
+		return nil, c.write(ctx, request)
+	})
+	return err
+}
+
+// notify 发送一个通知
+func (c *clientCore) notify(method string, params map[string]interface{}) error {
+	return c.write(context.Background(), Notification{
+		JsonRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// receive 获取下一个未被 call 认领的响应，timeout 为 0 时一直等待
+func (c *clientCore) receive(timeout time.Duration) (map[string]interface{}, error) {
+	var timer <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	for {
+		c.mu.Lock()
+		if len(c.queue) > 0 {
+			response := c.queue[0]
+			c.queue = c.queue[1:]
+			c.mu.Unlock()
+			return response, nil
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-c.queued:
+		case <-c.closed:
+			// 连接关闭前收到的响应仍然可以被读取
+			c.mu.Lock()
+			empty := len(c.queue) == 0
+			c.mu.Unlock()
+			if empty {
+				return nil, c.closeErr
+			}
+		case <-timer:
+			return nil, ErrTimeout
+		}
+	}
+}
+
+func (c *clientCore) intercept(ctx context.Context, request *Request, invoker Invoker) (json.RawMessage, error) {
+	for i := len(c.opts.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := c.opts.interceptors[i], invoker
+		invoker = func(ctx context.Context, request *Request) (json.RawMessage, error) {
+			return interceptor(ctx, request, next)
+		}
+	}
+	return invoker(ctx, request)
+}
+
+// roundTrip 分配请求 id，发送请求并等待对应的响应
+func (c *clientCore) roundTrip(ctx context.Context, request *Request) (json.RawMessage, error) {
+	start := time.Now()
+	result, err := c.calls.roundTrip(ctx, request, c.write, c.closed, func() error { return c.closeErr })
+
+	var rpcErr *Error
+	if err == nil || errors.As(err, &rpcErr) {
+		c.logCall(ctx, request, time.Since(start), rpcErr)
+	}
+	return result, err
+}
+
+// logCall 记录请求的结果，服务器返回错误时使用 Warn 级别
+func (c *clientCore) logCall(ctx context.Context, request *Request, duration time.Duration, rpcErr *Error) {
+	attrs := []slog.Attr{
+		slog.String("method", request.Method),
+		slog.Int("id", request.ID),
+		slog.Duration("duration", duration),
+	}
+	level := slog.LevelDebug
+	if rpcErr != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.Int("code", int(rpcErr.Code)), slog.String("error", rpcErr.Message))
+	}
+	c.opts.log().LogAttrs(ctx, level, "request completed", attrs...)
+}
+
+func (c *clientCore) write(ctx context.Context, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	if err := c.transport.writeMessage(ctx, data); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// readMessages 持续读取消息并分发，直到连接关闭
+func (c *clientCore) readMessages() {
+	for {
+		data, err := c.transport.readMessage()
+		if err != nil {
+			c.mu.Lock()
+			c.notificationsDone = true
+			c.mu.Unlock()
+			c.signalNotifications()
+			c.shutdown(err)
+			return
+		}
+		c.handleMessage(data)
+	}
+}
+
+func (c *clientCore) handleMessage(data []byte) {
+	var (
+		msg message
+		raw map[string]interface{}
+	)
+	if err := json.Unmarshal(data, &msg); err != nil {
+		c.opts.log().Warn("failed to unmarshal message", slog.Any("error", err))
+		return
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		c.opts.log().Warn("failed to unmarshal message", slog.Any("error", err))
+		return
+	}
+
+	for _, intercept := range c.opts.messageInterceptors {
+		intercept(raw)
+	}
+
+	switch {
+	case msg.isNotification():
+		c.mu.Lock()
+		c.notifications = append(c.notifications, &msg)
+		c.mu.Unlock()
+		c.signalNotifications()
+		return
+	case !msg.isResponse():
+		// 服务器发来的请求在独立的 goroutine 中处理，避免阻塞消息读取
+		go safe(c.opts.log(), func() {
+			c.handleRequest(&msg)
+		})()
+		return
+	case c.calls.deliver(&msg):
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queue = append(c.queue, raw)
+	select {
+	case c.queued <- struct{}{}:
+	default:
+	}
+}
+
+// handleRequest 调用服务器请求对应的处理器并返回响应，未注册处理器时返回 MethodNotFound
+func (c *clientCore) handleRequest(msg *message) {
+	response := Response{
+		JsonRPC: "2.0",
+		ID:      msg.id(),
+	}
+
+	result, err := c.callRequestHandler(msg)
+	if err != nil {
+		response.Error = toError(err)
+	} else {
+		response.Result = result
+	}
+
+	if err := c.write(context.Background(), response); err != nil {
+		c.opts.log().Warn("failed to write response", slog.String("method", msg.Method), slog.Any("error", err))
+	}
+}
+
+func (c *clientCore) callRequestHandler(msg *message) (result interface{}, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			c.opts.log().Error("request handler panic",
+				slog.String("method", msg.Method),
+				slog.Any("panic", rec),
+				slog.String("stack", string(debug.Stack())),
+			)
+			result = nil
+			err = NewError(InternalError, fmt.Sprintf("Method panicked: %s", msg.Method), nil)
+		}
+	}()
+
+	handler, exists := c.opts.requestHandlers[msg.Method]
+	if !exists && msg.Method == "ping" {
+		handler, exists = ping, true
+	}
+	if !exists {
+		return nil, NewError(MethodNotFound, fmt.Sprintf("Method not found: %s", msg.Method), nil)
+	}
+	return handler(c.ctx, msg.Params)
+}
+
+// signalNotifications 唤醒处理通知的 goroutine
+func (c *clientCore) signalNotifications() {
+	select {
+	case c.notified <- struct{}{}:
+	default:
+	}
+}
+
+// handleNotifications 按到达顺序依次调用通知处理器，连接关闭后处理完队列中的通知再退出
+func (c *clientCore) handleNotifications() {
+	for {
+		c.mu.Lock()
+		if len(c.notifications) == 0 {
+			done := c.notificationsDone
+			c.mu.Unlock()
+			if done {
+				return
+			}
+			<-c.notified
+			continue
+		}
+		msg := c.notifications[0]
+		c.notifications[0] = nil
+		c.notifications = c.notifications[1:]
+		c.mu.Unlock()
+
+		for _, handler := range c.opts.notificationHandlers[msg.Method] {
+			c.callNotificationHandler(handler, msg)
+		}
+	}
+}
+
+func (c *clientCore) callNotificationHandler(handler NotificationHandler, msg *message) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			c.opts.log().Error("notification handler panic",
+				slog.String("method", msg.Method),
+				slog.Any("panic", rec),
+				slog.String("stack", string(debug.Stack())),
+			)
+		}
+	}()
+	handler(msg.Method, msg.Params)
+}
+
+// shutdown 记录连接关闭的原因并唤醒所有等待者
+func (c *clientCore) shutdown(err error) {
+	c.mu.Lock()
+	lostErr := c.lostErr
+	c.mu.Unlock()
+
+	if lostErr != nil {
+		c.opts.log().Warn("connection closed", slog.Any("error", lostErr))
+		err = fmt.Errorf("%w: %w", ErrClosed, lostErr)
+	} else if isClosedError(err) {
+		c.opts.log().Info("connection closed")
+		err = ErrClosed
+	} else {
+		c.opts.log().Warn("connection closed", slog.Any("error", err))
+		err = fmt.Errorf("%w: %v", ErrClosed, err)
+	}
+	c.mu.Lock()
+	c.closeErr = err
+	c.mu.Unlock()
+	if c.opts.roots != nil {
+		c.opts.roots.unsubscribe(c)
+	}
+	c.cancel()
+	close(c.closed)
+}
+
+// ResponseError 从 ReceiveResponse 返回的响应中提取服务器返回的错误
+//
+// 响应中不包含 error 字段时返回 nil，否则返回 *Error，调用方可以通过 errors.As 获取错误码：
+//
+//	var rpcErr *gomcp.Error
+//	if errors.As(gomcp.ResponseError(response), &rpcErr) && rpcErr.Code == gomcp.InvalidParams {
+//		...
+//	}
+func ResponseError(response map[string]interface{}) error {
+	raw, ok := response["error"]
+	if !ok || raw == nil {
+		return nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response error: %w", err)
+	}
+	var rpcErr Error
+	if err := json.Unmarshal(data, &rpcErr); err != nil {
+		return fmt.Errorf("failed to unmarshal response error: %w", err)
+	}
+	return &rpcErr
+}
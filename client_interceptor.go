@@ -0,0 +1,71 @@
+package gomcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// RetryPolicy 请求失败时的重试策略
+type RetryPolicy struct {
+	// MaxAttempts 最大尝试次数（包含第一次），小于等于 1 时不重试
+	MaxAttempts int
+	// Backoff 第一次重试前的等待时间，之后每次翻倍
+	Backoff time.Duration
+	// MaxBackoff 等待时间的上限，为 0 时不限制
+	MaxBackoff time.Duration
+	// AttemptTimeout 单次尝试的超时时间，为 0 时只受调用方 ctx 的限制
+	AttemptTimeout time.Duration
+	// Retryable 判断错误是否可以重试，为空时使用 IsTransientError，只重试没有发出的请求。
+	// 服务器可能已经处理了超时或返回 InternalError 的请求，只有请求可以安全地重复执行时才应重试这些错误
+	Retryable func(err error) bool
+}
+
+// RetryInterceptor 返回一个按照 policy 重试失败请求的拦截器。
+// 每次重试都会重新调用 invoker，因此会分配新的请求 id
+func RetryInterceptor(policy RetryPolicy) Interceptor {
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsTransientError
+	}
+
+	return func(ctx context.Context, request *Request, invoker Invoker) (json.RawMessage, error) {
+		backoff := policy.Backoff
+		for attempt := 1; ; attempt++ {
+			result, err := invokeAttempt(ctx, request, invoker, policy.AttemptTimeout)
+			if err == nil || attempt >= policy.MaxAttempts || ctx.Err() != nil || !retryable(err) {
+				return result, err
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+			if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+				backoff = policy.MaxBackoff
+			}
+		}
+	}
+}
+
+func invokeAttempt(ctx context.Context, request *Request, invoker Invoker, timeout time.Duration) (json.RawMessage, error) {
+	if timeout <= 0 {
+		return invoker(ctx, request)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return invoker(ctx, request)
+}
+
+// IsTransientError 判断错误是否为可以安全重试的暂时性错误：请求在发送给服务器之前就失败了，
+// 例如 HTTP 连接失败。连接已关闭、发送或等待响应时超时以及服务器返回的错误都不视为暂时性错误，
+// 因为服务器可能已经处理了请求
+func IsTransientError(err error) bool {
+	var sendErr *sendError
+	return errors.As(err, &sendErr) && !errors.Is(err, ErrClosed) &&
+		!errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
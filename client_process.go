@@ -0,0 +1,134 @@
+package gomcp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultTerminateGrace 是关闭时发送 SIGTERM 后等待子进程退出的时间，超时后发送 SIGKILL
+const defaultTerminateGrace = 5 * time.Second
+
+// ProcessClient 通过子进程的标准输入输出与 MCP 服务器通信，由 Config.Connect 创建。
+// Close 会关闭输入输出流，发送 SIGTERM 并在宽限期后发送 SIGKILL，直到子进程退出
+type ProcessClient struct {
+	Client
+	cmd        *exec.Cmd
+	grace      time.Duration
+	initResult *InitializeResult
+	exited     chan struct{}
+	exitErr    error // 子进程退出的原因，exited 关闭后可读
+	closeOnce  sync.Once
+	closeErr   error
+}
+
+// startProcessClient 启动子进程并在其标准输入输出上建立客户端
+func startProcessClient(cmd *exec.Cmd, opts ...ClientOption) (*ProcessClient, error) {
+	// 使用独立的管道而不是 StdinPipe/StdoutPipe，cmd.Wait 不会在读取完成前关闭管道
+	stdinReader, stdinWriter, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		stdinReader.Close()
+		stdinWriter.Close()
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	cmd.Stdin = stdinReader
+	cmd.Stdout = stdoutWriter
+	err = cmd.Start()
+	// 子进程持有管道的另一端，父进程中的副本不再需要
+	stdinReader.Close()
+	stdoutWriter.Close()
+	if err != nil {
+		stdinWriter.Close()
+		stdoutReader.Close()
+		return nil, fmt.Errorf("failed to start server: %w", err)
+	}
+
+	client := &ProcessClient{
+		Client: NewStdioClient(stdoutReader, stdinWriter, opts...),
+		cmd:    cmd,
+		grace:  defaultTerminateGrace,
+		exited: make(chan struct{}),
+	}
+	options := newClientOptions(opts)
+	go safe(options.log(), func() {
+		client.exitErr = cmd.Wait()
+		close(client.exited)
+	})()
+	return client, nil
+}
+
+// Close 关闭连接并终止子进程，等待子进程退出后返回，可以重复调用
+func (c *ProcessClient) Close() error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.terminate()
+	})
+	return c.closeErr
+}
+
+// terminate 关闭输入输出流，先发送 SIGTERM，宽限期内未退出时发送 SIGKILL
+func (c *ProcessClient) terminate() error {
+	_ = c.Client.Close()
+
+	select {
+	case <-c.exited:
+		return nil
+	default:
+	}
+
+	// 不支持 SIGTERM 的平台直接结束进程
+	if err := c.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		return c.kill()
+	}
+
+	timer := time.NewTimer(c.grace)
+	defer timer.Stop()
+	select {
+	case <-c.exited:
+		return nil
+	case <-timer.C:
+		return c.kill()
+	}
+}
+
+// kill 强制结束子进程并等待其退出
+func (c *ProcessClient) kill() error {
+	if err := c.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to kill server: %w", err)
+	}
+	<-c.exited
+	return nil
+}
+
+// Pid 返回子进程的进程号
+func (c *ProcessClient) Pid() int {
+	return c.cmd.Process.Pid
+}
+
+// InitializeResult 返回握手时服务器返回的协议版本、能力与服务器信息
+func (c *ProcessClient) InitializeResult() *InitializeResult {
+	return c.initResult
+}
+
+// Exited 返回在子进程退出时关闭的 channel
+func (c *ProcessClient) Exited() <-chan struct{} {
+	return c.exited
+}
+
+// ExitErr 返回子进程退出的原因，正常退出时为 nil，子进程尚未退出时也返回 nil
+func (c *ProcessClient) ExitErr() error {
+	select {
+	case <-c.exited:
+		return c.exitErr
+	default:
+		return nil
+	}
+}
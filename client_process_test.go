@@ -0,0 +1,114 @@
+package gomcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// 作为子进程运行的 MCP 服务器，由 helperServerConfig 启动。
+// GOMCP_HELPER_MODE 为 hang 时忽略 SIGTERM，并且在输入流关闭后不退出；为 fail 时启动后立即退出
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GOMCP_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, "helper started")
+	if os.Getenv("GOMCP_HELPER_MODE") == "fail" {
+		fmt.Fprintln(os.Stderr, "helper failing")
+		os.Exit(2)
+	}
+
+	hang := os.Getenv("GOMCP_HELPER_MODE") == "hang"
+	if hang {
+		signal.Ignore(syscall.SIGTERM)
+	}
+
+	server := NewStdioServer(os.Stdin, os.Stdout, WithServerInfo("helper", "1.0"),
+		WithServerLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	server.RegisterTool(Tool{Name: "echo"}, func(ctx context.Context, arguments map[string]interface{}) (*CallToolResult, error) {
+		text, _ := arguments["text"].(string)
+		return &CallToolResult{Content: []Content{TextContent(text)}}, nil
+	})
+	server.RegisterTool(Tool{Name: "env"}, func(ctx context.Context, arguments map[string]interface{}) (*CallToolResult, error) {
+		name, _ := arguments["name"].(string)
+		return &CallToolResult{Content: []Content{TextContent(os.Getenv(name))}}, nil
+	})
+	server.RegisterTool(Tool{Name: "cwd"}, func(ctx context.Context, arguments map[string]interface{}) (*CallToolResult, error) {
+		dir, err := os.Getwd()
+		return &CallToolResult{Content: []Content{TextContent(dir)}}, err
+	})
+	server.RegisterTool(Tool{Name: "sleep"}, func(ctx context.Context, arguments map[string]interface{}) (*CallToolResult, error) {
+		millis, _ := arguments["ms"].(float64)
+		time.Sleep(time.Duration(millis) * time.Millisecond)
+		return &CallToolResult{}, nil
+	})
+	server.RegisterTool(Tool{Name: "exit"}, func(ctx context.Context, arguments map[string]interface{}) (*CallToolResult, error) {
+		os.Exit(3)
+		return nil, nil
+	})
+
+	err := server.Serve(context.Background())
+	if hang {
+		select {}
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// helperServerConfig 返回以当前测试程序作为 MCP 服务器子进程的配置
+func helperServerConfig(mode string) ServerConfig {
+	return ServerConfig{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestHelperProcess$"},
+		Env: map[string]string{
+			"GOMCP_HELPER_PROCESS": "1",
+			"GOMCP_HELPER_MODE":    mode,
+		},
+	}
+}
+
+// 测试子进程客户端 - 子进程不响应 SIGTERM 时在宽限期后强制结束
+func TestProcessClient_Kill(t *testing.T) {
+	config := &Config{MCPServers: map[string]ServerConfig{"hang": helperServerConfig("hang")}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := config.Connect(ctx, "hang")
+	if err != nil {
+		t.Fatalf("连接服务器失败: %v", err)
+	}
+	process := client.(*ProcessClient)
+	process.grace = 100 * time.Millisecond
+
+	start := time.Now()
+	if err := client.Close(); err != nil {
+		t.Errorf("关闭客户端失败: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < process.grace {
+		t.Errorf("应该在宽限期之后强制结束, 实际耗时 %v", elapsed)
+	}
+
+	select {
+	case <-process.Exited():
+	default:
+		t.Fatal("Close 返回时子进程应该已经退出")
+	}
+	if process.ExitErr() == nil {
+		t.Error("被强制结束的子进程应该返回退出错误")
+	}
+
+	// 重复关闭是安全的
+	if err := client.Close(); err != nil {
+		t.Errorf("重复关闭失败: %v", err)
+	}
+}
@@ -0,0 +1,517 @@
+package gomcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RemoteClient 实现了基于远程传输层（Streamable HTTP、HTTP+SSE 与 WebSocket）的 MCP 客户端
+type RemoteClient struct {
+	core clientCore
+}
+
+func newRemoteClient(t transport, opts []ClientOption) *RemoteClient {
+	client := &RemoteClient{
+		core: clientCore{opts: newClientOptions(opts)},
+	}
+	client.core.start(t)
+	return client
+}
+
+// NewHTTPClient 创建一个使用 Streamable HTTP 传输的 MCP 客户端，header 会附加到每个 HTTP 请求中。
+// 每条消息通过 POST 发送，服务器可以直接返回 JSON 或以 SSE 流返回；
+// 服务器分配会话后，客户端会通过 GET 打开 SSE 流接收服务器主动发送的消息
+func NewHTTPClient(endpoint string, header http.Header, opts ...ClientOption) (Client, error) {
+	if _, err := url.ParseRequestURI(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid server url: %w", err)
+	}
+	options := newClientOptions(opts)
+	return newRemoteClient(newHTTPTransport(endpoint, header, options.http(), options.log()), opts), nil
+}
+
+// NewSSEClient 创建一个使用 HTTP+SSE 传输（2024-11-05 版本协议）的 MCP 客户端，
+// 连接建立后服务器通过 endpoint 事件告知发送消息的地址，该地址必须与 endpoint 同源。
+// ctx 只用于建立连接，连接建立后取消 ctx 不会关闭客户端
+func NewSSEClient(ctx context.Context, endpoint string, header http.Header, opts ...ClientOption) (Client, error) {
+	options := newClientOptions(opts)
+	t, err := dialSSE(ctx, endpoint, header, options.http(), options.log())
+	if err != nil {
+		return nil, err
+	}
+	return newRemoteClient(t, opts), nil
+}
+
+// Close 关闭客户端连接
+func (c *RemoteClient) Close() error {
+	return c.core.close()
+}
+
+// SendRequest 发送 MCP 请求
+func (c *RemoteClient) SendRequest(method string, params map[string]interface{}) error {
+	return c.core.send(method, params)
+}
+
+// ReceiveResponse 接收 MCP 响应，连接关闭时返回 nil
+func (c *RemoteClient) ReceiveResponse() (map[string]interface{}, error) {
+	response, err := c.core.receive(0)
+	if err == ErrClosed {
+		return nil, nil
+	}
+	return response, err
+}
+
+// Call 发送请求并等待对应的响应
+func (c *RemoteClient) Call(ctx context.Context, method string, params map[string]interface{}) (json.RawMessage, error) {
+	return c.core.call(ctx, method, params)
+}
+
+// Notify 向服务器发送一个通知
+func (c *RemoteClient) Notify(method string, params map[string]interface{}) error {
+	return c.core.notify(method, params)
+}
+
+// Done 返回在连接关闭时关闭的 channel
+func (c *RemoteClient) Done() <-chan struct{} {
+	return c.core.closed
+}
+
+func (c *RemoteClient) setProtocolVersion(version string) {
+	if t, ok := c.core.transport.(protocolVersionSetter); ok {
+		t.setProtocolVersion(version)
+	}
+}
+
+// protocolVersionSetter 由需要知道协商后协议版本的客户端与传输层实现，Initialize 在握手成功后调用
+type protocolVersionSetter interface {
+	setProtocolVersion(version string)
+}
+
+// mcpSessionHeader 是 Streamable HTTP 传输中携带会话 id 的请求头
+const mcpSessionHeader = "Mcp-Session-Id"
+
+// mcpProtocolVersionHeader 是 Streamable HTTP 传输中携带协商后协议版本的请求头
+const mcpProtocolVersionHeader = "MCP-Protocol-Version"
+
+// httpTransport 是 Streamable HTTP 传输的客户端
+type httpTransport struct {
+	endpoint string
+	header   http.Header
+	client   *http.Client
+	logger   *slog.Logger
+	ctx      context.Context // close 时取消，结束所有进行中的 HTTP 请求
+	cancel   context.CancelFunc
+	incoming chan []byte
+
+	mu              sync.Mutex // 保护下面的字段
+	sessionID       string
+	protocolVersion string
+	listening       bool
+}
+
+func newHTTPTransport(endpoint string, header http.Header, client *http.Client, logger *slog.Logger) *httpTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &httpTransport{
+		endpoint: endpoint,
+		header:   header,
+		client:   client,
+		logger:   logger,
+		ctx:      ctx,
+		cancel:   cancel,
+		incoming: make(chan []byte, 16),
+	}
+}
+
+func (t *httpTransport) readMessage() ([]byte, error) {
+	select {
+	case data := <-t.incoming:
+		return data, nil
+	case <-t.ctx.Done():
+		return nil, io.EOF
+	}
+}
+
+// deliver 将收到的消息交给 readMessage
+func (t *httpTransport) deliver(data []byte) {
+	select {
+	case t.incoming <- data:
+	case <-t.ctx.Done():
+	}
+}
+
+// setProtocolVersion 记录协商后的协议版本，之后的请求都会携带 MCP-Protocol-Version 请求头
+func (t *httpTransport) setProtocolVersion(version string) {
+	t.mu.Lock()
+	t.protocolVersion = version
+	t.mu.Unlock()
+}
+
+// newRequest 创建携带自定义请求头、会话 id 与协议版本的 HTTP 请求
+func (t *httpTransport) newRequest(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range t.header {
+		req.Header[key] = values
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set(mcpSessionHeader, t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set(mcpProtocolVersionHeader, t.protocolVersion)
+	}
+	t.mu.Unlock()
+	return req, nil
+}
+
+// writeMessage 通过 POST 发送一条消息，ctx 结束或传输层关闭时取消请求。
+// 以 SSE 流返回的响应在后台读取，同样在 ctx 结束时停止
+func (t *httpTransport) writeMessage(ctx context.Context, data []byte) error {
+	ctx, cancel := mergeCancel(ctx, t.ctx)
+	req, err := t.newRequest(ctx, http.MethodPost, bytes.NewReader(data))
+	if err != nil {
+		cancel()
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		cancel()
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		cancel()
+		return fmt.Errorf("unexpected http status: %s", resp.Status)
+	}
+
+	if sessionID := resp.Header.Get(mcpSessionHeader); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+		t.listen()
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		resp.Body.Close()
+		cancel()
+	case mediaType == "text/event-stream":
+		// 响应以 SSE 流返回，流中可能还包含服务器在处理过程中发出的请求与通知
+		go safe(t.logger, func() {
+			defer cancel()
+			defer resp.Body.Close()
+			if err := t.readEvents(resp.Body); err != nil {
+				t.logger.Debug("http response stream closed", slog.Any("error", err))
+			}
+		})()
+	default:
+		defer cancel()
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read http response: %w", err)
+		}
+		if len(bytes.TrimSpace(body)) > 0 {
+			t.deliver(body)
+		}
+	}
+	return nil
+}
+
+// mergeCancel 返回在 ctx 或 parent 任意一个结束时取消的 context，
+// 用于让 HTTP 请求同时受调用方与传输层生命周期的限制
+func mergeCancel(ctx, parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(parent, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// listen 在获得会话 id 后打开一次 GET SSE 流，接收服务器主动发送的消息。服务器不支持时忽略
+func (t *httpTransport) listen() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.listening {
+		return
+	}
+	t.listening = true
+
+	go safe(t.logger, func() {
+		req, err := t.newRequest(t.ctx, http.MethodGet, nil)
+		if err != nil {
+			return
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		resp, err := t.client.Do(req)
+		if err != nil {
+			t.logger.Debug("failed to open http event stream", slog.Any("error", err))
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.logger.Debug("server does not offer an http event stream", slog.String("status", resp.Status))
+			return
+		}
+		if err := t.readEvents(resp.Body); err != nil {
+			t.logger.Debug("http event stream closed", slog.Any("error", err))
+		}
+	})()
+}
+
+// readEvents 将 SSE 流中的 message 事件交给 readMessage
+func (t *httpTransport) readEvents(r io.Reader) error {
+	return readSSE(r, func(event sseEvent) {
+		if event.name == "" || event.name == "message" {
+			t.deliver([]byte(event.data))
+		}
+	})
+}
+
+// close 结束所有 HTTP 请求，并通知服务器删除会话
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+
+	if sessionID != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if req, err := t.newRequest(ctx, http.MethodDelete, nil); err == nil {
+			if resp, err := t.client.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}
+	t.cancel()
+	return nil
+}
+
+// sseTransport 是 HTTP+SSE 传输的客户端：通过 GET 接收 SSE 事件，通过 POST 向 endpoint 发送消息
+type sseTransport struct {
+	header   http.Header
+	client   *http.Client
+	logger   *slog.Logger
+	body     io.ReadCloser
+	ctx      context.Context // close 时取消，结束 SSE 流与进行中的 POST 请求
+	cancel   context.CancelFunc
+	events   chan []byte
+	ready    chan struct{} // 收到 endpoint 事件后关闭
+	endpoint string
+	done     chan struct{} // SSE 流结束后关闭
+	err      error         // SSE 流结束的原因，done 关闭后可读
+	closed   sync.Once
+}
+
+// dialSSE 打开 SSE 流并在后台读取事件，ctx 只用于等待服务器的响应头
+func dialSSE(ctx context.Context, endpoint string, header http.Header, client *http.Client, logger *slog.Logger) (*sseTransport, error) {
+	base, err := url.ParseRequestURI(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server url: %w", err)
+	}
+
+	// SSE 流的生命周期与客户端相同，不能直接使用 ctx，只在建立连接期间跟随 ctx 取消
+	streamCtx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	stop := context.AfterFunc(ctx, cancel)
+	resp, err := client.Do(req)
+	if !stop() {
+		// ctx 已经结束，请求已被取消或即将被取消
+		if err == nil {
+			resp.Body.Close()
+		}
+		return nil, fmt.Errorf("failed to connect to server: %w", ctx.Err())
+	}
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("unexpected http status: %s", resp.Status)
+	}
+
+	t := &sseTransport{
+		header: header,
+		client: client,
+		logger: logger,
+		body:   resp.Body,
+		ctx:    streamCtx,
+		cancel: cancel,
+		events: make(chan []byte, 16),
+		ready:  make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go safe(logger, func() {
+		err := readSSE(resp.Body, func(event sseEvent) {
+			switch event.name {
+			case "endpoint":
+				if err := t.setEndpoint(base, event.data); err != nil {
+					t.logger.Warn("invalid sse endpoint", slog.Any("error", err))
+					t.finish(err)
+				}
+			case "", "message":
+				select {
+				case t.events <- []byte(event.data):
+				case <-t.done:
+				}
+			}
+		})
+		t.finish(err)
+	})()
+	return t, nil
+}
+
+// setEndpoint 记录发送消息的地址，只有第一个 endpoint 事件生效。
+// 地址相对于 SSE 地址解析，与 SSE 地址不同源时返回错误，避免把消息发送给其他服务器
+func (t *sseTransport) setEndpoint(base *url.URL, endpoint string) error {
+	select {
+	case <-t.ready:
+		return nil
+	default:
+	}
+	ref, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil {
+		return fmt.Errorf("invalid sse endpoint %q: %w", endpoint, err)
+	}
+	resolved := base.ResolveReference(ref)
+	if resolved.Scheme != base.Scheme || resolved.Host != base.Host {
+		return fmt.Errorf("sse endpoint %s does not match the origin of %s", resolved.Redacted(), base.Redacted())
+	}
+	t.endpoint = resolved.String()
+	close(t.ready)
+	return nil
+}
+
+func (t *sseTransport) readMessage() ([]byte, error) {
+	select {
+	case data := <-t.events:
+		return data, nil
+	case <-t.done:
+		if t.err != nil && !errors.Is(t.err, context.Canceled) && !isClosedError(t.err) {
+			return nil, t.err
+		}
+		return nil, io.EOF
+	}
+}
+
+// writeMessage 等待服务器告知 endpoint 后通过 POST 发送一条消息，ctx 结束或传输层关闭时放弃
+func (t *sseTransport) writeMessage(ctx context.Context, data []byte) error {
+	select {
+	case <-t.ready:
+	case <-t.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	ctx, cancel := mergeCancel(ctx, t.ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for key, values := range t.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected http status: %s", resp.Status)
+	}
+	return nil
+}
+
+func (t *sseTransport) close() error {
+	t.finish(nil)
+	return nil
+}
+
+// finish 关闭 SSE 流并记录结束的原因，只有第一次调用生效
+func (t *sseTransport) finish(err error) {
+	t.closed.Do(func() {
+		t.err = err
+		t.cancel()
+		t.body.Close()
+		close(t.done)
+	})
+}
+
+// sseEvent 是 SSE 流中的一个事件
+type sseEvent struct {
+	name string
+	data string
+}
+
+// readSSE 解析 SSE 流并对每个事件调用 handle，直到流结束
+func readSSE(r io.Reader, handle func(sseEvent)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var (
+		event sseEvent
+		data  []string
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// 空行表示事件结束
+			if len(data) > 0 {
+				event.data = strings.Join(data, "\n")
+				handle(event)
+			}
+			event, data = sseEvent{}, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // 注释，通常用于保持连接
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.name = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
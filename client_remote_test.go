@@ -0,0 +1,446 @@
+package gomcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// remoteTestResponse 模拟远程 MCP 服务器处理一条消息，通知返回 nil
+func remoteTestResponse(t *testing.T, data []byte) []byte {
+	var msg struct {
+		ID     json.RawMessage        `json:"id"`
+		Method string                 `json:"method"`
+		Params map[string]interface{} `json:"params"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Errorf("解析消息失败: %v", err)
+		return nil
+	}
+	if msg.ID == nil {
+		return nil
+	}
+
+	var result interface{}
+	switch msg.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"protocolVersion": LatestProtocolVersion,
+			"capabilities":    map[string]interface{}{},
+			"serverInfo":      map[string]interface{}{"name": "remote", "version": "1.0.0"},
+		}
+	default:
+		result = msg.Params
+	}
+	response, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID, "result": result})
+	return response
+}
+
+// roundTripperFunc 将函数适配为 http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// 测试 Streamable HTTP 客户端：JSON 响应、SSE 响应、GET 事件流、会话 id 与请求头
+func TestHTTPClient_Communication(t *testing.T) {
+	var (
+		mu            sync.Mutex
+		authorization []string
+		versions      = map[string]string{} // 方法 -> MCP-Protocol-Version 请求头
+		deleted       bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		mu.Unlock()
+
+		switch r.Method {
+		case http.MethodPost:
+			data, _ := io.ReadAll(r.Body)
+			var msg message
+			json.Unmarshal(data, &msg)
+			mu.Lock()
+			versions[msg.Method] = r.Header.Get(mcpProtocolVersionHeader)
+			mu.Unlock()
+
+			w.Header().Set(mcpSessionHeader, "session-1")
+			response := remoteTestResponse(t, data)
+			if response == nil {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			if msg.Method != "stream" {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(response)
+				return
+			}
+			// 以 SSE 流返回，先发送一个通知
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\",\"params\":{\"progress\":1}}\n\n")
+			fmt.Fprintf(w, ": keepalive\n\ndata: %s\n\n", response)
+		case http.MethodGet:
+			if r.Header.Get(mcpSessionHeader) != "session-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\",\"params\":{\"data\":\"hello\"}}\n\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		case http.MethodDelete:
+			mu.Lock()
+			deleted = r.Header.Get(mcpSessionHeader) == "session-1"
+			mu.Unlock()
+		}
+	}))
+	defer server.Close()
+
+	notifications := make(chan string, 10)
+	handler := func(method string, params map[string]interface{}) {
+		notifications <- method
+	}
+	var requests atomic.Int32
+	httpClient := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		requests.Add(1)
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+
+	config := &Config{MCPServers: map[string]ServerConfig{
+		"remote": {Type: TransportHTTP, URL: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}},
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := config.Connect(ctx, "remote",
+		WithNotificationHandler("notifications/progress", handler),
+		WithNotificationHandler("notifications/message", handler),
+		WithHTTPClient(httpClient),
+	)
+	if err != nil {
+		t.Fatalf("连接服务器失败: %v", err)
+	}
+	if _, ok := client.(*RemoteClient); !ok {
+		t.Fatalf("客户端类型: 期望 *RemoteClient, 得到 %T", client)
+	}
+
+	// JSON 响应
+	result, err := client.Call(ctx, "echo", map[string]interface{}{"message": "json"})
+	if err != nil {
+		t.Fatalf("调用 echo 失败: %v", err)
+	}
+	if string(result) != `{"message":"json"}` {
+		t.Errorf("echo 结果: 期望 %v, 得到 %v", `{"message":"json"}`, string(result))
+	}
+
+	// SSE 响应
+	result, err = client.Call(ctx, "stream", map[string]interface{}{"message": "sse"})
+	if err != nil {
+		t.Fatalf("调用 stream 失败: %v", err)
+	}
+	if string(result) != `{"message":"sse"}` {
+		t.Errorf("stream 结果: 期望 %v, 得到 %v", `{"message":"sse"}`, string(result))
+	}
+
+	received := map[string]bool{}
+	for len(received) < 2 {
+		select {
+		case method := <-notifications:
+			received[method] = true
+		case <-time.After(2 * time.Second):
+			t.Fatalf("等待通知超时, 已收到 %v", received)
+		}
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("关闭客户端失败: %v", err)
+	}
+
+	if requests.Load() == 0 {
+		t.Error("应该使用 WithHTTPClient 设置的 *http.Client 发送请求")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// initialize 之后的请求都应该携带协商后的协议版本
+	expectedVersions := map[string]string{
+		"initialize":                "",
+		"notifications/initialized": LatestProtocolVersion,
+		"echo":                      LatestProtocolVersion,
+		"stream":                    LatestProtocolVersion,
+	}
+	if !reflect.DeepEqual(versions, expectedVersions) {
+		t.Errorf("协议版本请求头: 期望 %v, 得到 %v", expectedVersions, versions)
+	}
+	if !deleted {
+		t.Error("关闭客户端时应删除服务器会话")
+	}
+	for _, value := range authorization {
+		if value != "Bearer token" {
+			t.Errorf("请求头: 期望 %v, 得到 %v", "Bearer token", value)
+		}
+	}
+}
+
+// 测试 HTTP+SSE 客户端通过 endpoint 事件获得发送地址
+func TestSSEClient_Communication(t *testing.T) {
+	responses := make(chan []byte, 10)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: endpoint\ndata: /messages?session=1\n\n")
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case response := <-responses:
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", response)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("session") != "1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		if response := remoteTestResponse(t, data); response != nil {
+			responses <- response
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	config := &Config{MCPServers: map[string]ServerConfig{
+		"remote": {Type: TransportSSE, URL: server.URL + "/sse"},
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := config.Connect(ctx, "remote")
+	if err != nil {
+		t.Fatalf("连接服务器失败: %v", err)
+	}
+	defer client.Close()
+
+	result, err := client.Call(ctx, "echo", map[string]interface{}{"message": "sse"})
+	if err != nil {
+		t.Fatalf("调用 echo 失败: %v", err)
+	}
+	if string(result) != `{"message":"sse"}` {
+		t.Errorf("echo 结果: 期望 %v, 得到 %v", `{"message":"sse"}`, string(result))
+	}
+
+	// 服务器关闭后连接应当结束
+	server.CloseClientConnections()
+	select {
+	case <-client.(*RemoteClient).Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("服务器断开后客户端应当关闭")
+	}
+}
+
+// 测试 WebSocket 客户端的握手、消息收发与 ping 响应
+func TestWebSocketClient_Communication(t *testing.T) {
+	pongs := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Sec-WebSocket-Protocol") != "mcp" || r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("接管连接失败: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\nSec-WebSocket-Protocol: mcp\r\n\r\n",
+			websocketAccept(r.Header.Get("Sec-WebSocket-Key")))
+		writeWebSocketFrame(rw, wsPing, []byte("ping"), false)
+		rw.Flush()
+
+		reader := bufio.NewReader(rw)
+		for {
+			_, opcode, payload, err := readWebSocketFrame(reader)
+			if err != nil {
+				return
+			}
+			switch opcode {
+			case wsPong:
+				pongs <- string(payload)
+			case wsClose:
+				return
+			case wsText:
+				if response := remoteTestResponse(t, payload); response != nil {
+					writeWebSocketFrame(rw, wsText, response, false)
+					rw.Flush()
+				}
+			}
+		}
+	}))
+	defer server.Close()
+
+	config := &Config{MCPServers: map[string]ServerConfig{
+		"remote": {URL: "ws" + server.URL[len("http"):], Headers: map[string]string{"X-Token": "secret"}},
+	}}
+	// 未设置 type 时根据 ws:// 地址推断传输类型
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := config.Connect(ctx, "remote")
+	if err != nil {
+		t.Fatalf("连接服务器失败: %v", err)
+	}
+	defer client.Close()
+
+	// 超过 125 字节的消息使用扩展长度
+	message := string(make([]byte, 200))
+	result, err := client.Call(ctx, "echo", map[string]interface{}{"message": message})
+	if err != nil {
+		t.Fatalf("调用 echo 失败: %v", err)
+	}
+	var echoed map[string]string
+	if err := json.Unmarshal(result, &echoed); err != nil || echoed["message"] != message {
+		t.Errorf("echo 结果不一致: %v", err)
+	}
+
+	select {
+	case payload := <-pongs:
+		if payload != "ping" {
+			t.Errorf("pong 负载: 期望 %v, 得到 %v", "ping", payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("客户端应当响应 ping")
+	}
+}
+
+// 测试 HTTP+SSE 客户端拒绝与 SSE 地址不同源的 endpoint
+func TestSSEClient_CrossOriginEndpoint(t *testing.T) {
+	var posted atomic.Bool
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted.Store(true)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: endpoint\ndata: %s/messages\n\n", other.URL)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := NewSSEClient(ctx, server.URL, nil)
+	if err != nil {
+		t.Fatalf("连接服务器失败: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Call(ctx, "ping", nil); err == nil {
+		t.Error("不同源的 endpoint 应该导致请求失败")
+	}
+	if posted.Load() {
+		t.Error("不应该向不同源的 endpoint 发送消息")
+	}
+}
+
+// 测试 WebSocket 客户端拒绝没有选择 mcp 子协议的握手响应
+func TestWebSocketClient_Subprotocol(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("接管连接失败: %v", err)
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+			websocketAccept(r.Header.Get("Sec-WebSocket-Key")))
+		rw.Flush()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := NewWebSocketClient(ctx, "ws"+server.URL[len("http"):], nil)
+	if err == nil || !strings.Contains(err.Error(), "subprotocol") {
+		t.Errorf("应该返回子协议错误, 得到 %v", err)
+	}
+
+	// ctx 已经结束时不建立连接
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	if _, err := NewWebSocketClient(canceled, "ws"+server.URL[len("http"):], nil); err == nil {
+		t.Error("ctx 结束后应该返回错误")
+	}
+}
+
+// 测试写入消息时遵守调用方的 ctx：服务器迟迟不返回 endpoint 或不响应 POST 时，请求随 ctx 结束
+func TestRemoteClient_WriteContext(t *testing.T) {
+	var sendEndpoint atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		if sendEndpoint.Load() {
+			fmt.Fprint(w, "event: endpoint\ndata: /messages\n\n")
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	stalled := make(chan struct{})
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-stalled:
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	defer close(stalled)
+
+	call := func(client Client) time.Duration {
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if _, err := client.Call(ctx, "ping", nil); err == nil {
+			t.Error("ctx 结束后应该返回错误")
+		}
+		return time.Since(start)
+	}
+
+	for _, withEndpoint := range []bool{false, true} {
+		sendEndpoint.Store(withEndpoint)
+		client, err := NewSSEClient(context.Background(), server.URL+"/sse", nil)
+		if err != nil {
+			t.Fatalf("连接服务器失败: %v", err)
+		}
+		if elapsed := call(client); elapsed > 2*time.Second {
+			t.Errorf("SSE 请求 (endpoint=%v) 应当随 ctx 结束, 耗时 %v", withEndpoint, elapsed)
+		}
+		client.Close()
+	}
+
+	client, err := NewHTTPClient(server.URL+"/messages", nil)
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	defer client.Close()
+	if elapsed := call(client); elapsed > 2*time.Second {
+		t.Errorf("HTTP 请求应当随 ctx 结束, 耗时 %v", elapsed)
+	}
+}
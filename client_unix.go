@@ -1,25 +1,40 @@
 package gomcp
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io"
 	"net"
 )
 
 // UnixClient 实现了基于 Unix Domain Socket 的 MCP 客户端
 type UnixClient struct {
 	conn net.Conn
+	core clientCore
 }
 
 // NewUnixClient 创建一个新的 Unix Domain Socket MCP 客户端
-func NewUnixClient(socketPath string) (Client, error) {
-	conn, err := net.Dial("unix", socketPath)
+func NewUnixClient(socketPath string, opts ...ClientOption) (Client, error) {
+	return dialUnixClient(context.Background(), socketPath, opts...)
+}
+
+// dialUnixClient 连接 socketPath 并创建客户端，ctx 结束时放弃连接
+func dialUnixClient(ctx context.Context, socketPath string, opts ...ClientOption) (Client, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to socket: %w", err)
 	}
-	return &UnixClient{conn: conn}, nil
+	return newUnixClient(conn, opts...), nil
+}
+
+// newUnixClient 在已经建立的连接上创建客户端并启动消息读取
+func newUnixClient(conn net.Conn, opts ...ClientOption) *UnixClient {
+	client := &UnixClient{
+		conn: conn,
+		core: clientCore{opts: newClientOptions(opts)},
+	}
+	client.core.start(newStreamTransport(conn, conn, conn.Close))
+	return client
 }
 
 // Close 关闭客户端连接
@@ -29,24 +44,32 @@ func (c *UnixClient) Close() error {
 
 // SendRequest 发送 MCP 请求
 func (c *UnixClient) SendRequest(method string, params map[string]interface{}) error {
-	request := Request{
-		JsonRPC: "2.0",
-		Method:  method,
-		Params:  params,
-		ID:      1,
-	}
-	return json.NewEncoder(c.conn).Encode(request)
+	return c.core.send(method, params)
 }
 
-// ReceiveResponse 接收 MCP 响应
+// ReceiveResponse 接收 MCP 响应，连接关闭时返回 nil
 func (c *UnixClient) ReceiveResponse() (map[string]interface{}, error) {
-	var response map[string]interface{}
-	err := json.NewDecoder(c.conn).Decode(&response)
+	response, err := c.core.receive(0)
 	if err != nil {
-		if errors.Is(err, io.EOF) {
+		if err == ErrClosed {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 	return response, nil
 }
+
+// Call 发送请求并等待对应的响应
+func (c *UnixClient) Call(ctx context.Context, method string, params map[string]interface{}) (json.RawMessage, error) {
+	return c.core.call(ctx, method, params)
+}
+
+// Notify 向服务器发送一个通知
+func (c *UnixClient) Notify(method string, params map[string]interface{}) error {
+	return c.core.notify(method, params)
+}
+
+// Done 返回在连接关闭时关闭的 channel
+func (c *UnixClient) Done() <-chan struct{} {
+	return c.core.closed
+}
@@ -1,12 +1,15 @@
 package gomcp
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 )
@@ -23,18 +26,28 @@ func TestUnixClient_Communication(t *testing.T) {
 	socketPath := filepath.Join(tempDir, "test.sock")
 
 	// 创建并启动服务器
-	server := NewUnixServer(socketPath)
+	server := NewUnixServer(socketPath, WithPanicHandler(func(method string, rec any, stack []byte) {}))
 
 	// 注册一个回显处理器
-	server.RegisterHandler("echo", func(params map[string]interface{}) (interface{}, error) {
+	server.RegisterHandler("echo", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 		return params["message"], nil
 	})
 
 	// 注册一个返回错误的处理器
-	server.RegisterHandler("error", func(params map[string]interface{}) (interface{}, error) {
+	server.RegisterHandler("error", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 		return nil, errors.New("test error")
 	})
 
+	// 注册一个返回 *Error 的处理器
+	server.RegisterHandler("invalid_params", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return nil, NewError(InvalidParams, "invalid params", "bad input")
+	})
+
+	// 注册一个会 panic 的处理器
+	server.RegisterHandler("panic", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		panic("boom")
+	})
+
 	// 启动服务器
 	err = server.Start()
 	if err != nil {
@@ -105,6 +118,65 @@ func TestUnixClient_Communication(t *testing.T) {
 		}
 	})
 
+	// 测试服务器返回 *Error 的情况
+	t.Run("错误码透传", func(t *testing.T) {
+		err = client.SendRequest("invalid_params", map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("发送请求失败: %v", err)
+		}
+
+		response, err := client.ReceiveResponse()
+		if err != nil {
+			t.Fatalf("接收响应失败: %v", err)
+		}
+
+		var rpcErr *Error
+		if !errors.As(ResponseError(response), &rpcErr) {
+			t.Fatalf("应该能够通过 errors.As 获取 *Error, 响应: %v", response)
+		}
+
+		if rpcErr.Code != InvalidParams {
+			t.Errorf("错误代码错误: 期望 %d, 得到 %d", InvalidParams, rpcErr.Code)
+		}
+
+		if rpcErr.Data != "bad input" {
+			t.Errorf("错误数据错误: 期望 'bad input', 得到 %v", rpcErr.Data)
+		}
+	})
+
+	// 测试处理器 panic 后连接仍然可用
+	t.Run("处理器panic", func(t *testing.T) {
+		err = client.SendRequest("panic", map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("发送请求失败: %v", err)
+		}
+
+		response, err := client.ReceiveResponse()
+		if err != nil {
+			t.Fatalf("接收响应失败: %v", err)
+		}
+
+		var rpcErr *Error
+		if !errors.As(ResponseError(response), &rpcErr) || rpcErr.Code != InternalError {
+			t.Fatalf("应该返回 InternalError, 响应: %v", response)
+		}
+
+		// 同一连接上的后续请求应该正常处理
+		err = client.SendRequest("echo", map[string]interface{}{"message": "still alive"})
+		if err != nil {
+			t.Fatalf("发送请求失败: %v", err)
+		}
+
+		response, err = client.ReceiveResponse()
+		if err != nil {
+			t.Fatalf("接收响应失败: %v", err)
+		}
+
+		if response["result"] != "still alive" {
+			t.Errorf("响应中的result字段错误: 期望 'still alive', 得到 %v", response["result"])
+		}
+	})
+
 	// 测试方法不存在的情况
 	t.Run("方法不存在", func(t *testing.T) {
 		// 发送请求
@@ -155,7 +227,7 @@ func TestUnixClient_Close(t *testing.T) {
 	mockConn := &mockConn{}
 
 	// 创建客户端
-	client := &UnixClient{conn: mockConn}
+	client := newUnixClient(mockConn)
 
 	// 关闭连接
 	err := client.Close()
@@ -171,12 +243,15 @@ func TestUnixClient_Close(t *testing.T) {
 
 // mockConn 实现了net.Conn接口，用于测试
 type mockConn struct {
+	mu        sync.Mutex // 客户端在后台 goroutine 中读取
 	closed    bool
 	readData  []byte
 	writeData []byte
 }
 
 func (m *mockConn) Read(b []byte) (n int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.closed {
 		return 0, net.ErrClosed
 	}
@@ -189,6 +264,8 @@ func (m *mockConn) Read(b []byte) (n int, err error) {
 }
 
 func (m *mockConn) Write(b []byte) (n int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.closed {
 		return 0, net.ErrClosed
 	}
@@ -197,6 +274,8 @@ func (m *mockConn) Write(b []byte) (n int, err error) {
 }
 
 func (m *mockConn) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.closed = true
 	return nil
 }
@@ -232,7 +311,7 @@ func TestUnixClient_MockConnection(t *testing.T) {
 	}
 
 	// 创建客户端
-	client := &UnixClient{conn: mockConn}
+	client := newUnixClient(mockConn)
 
 	// 发送请求
 	err := client.SendRequest("test_method", map[string]interface{}{"key": "value"})
@@ -262,3 +341,207 @@ func TestUnixClient_MockConnection(t *testing.T) {
 		t.Errorf("响应中的result字段错误: 期望 'mock result', 得到 %v", response["result"])
 	}
 }
+
+// 测试客户端拦截器 - 修改请求、观察消息与重试
+func TestUnixClient_Interceptors(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "unix_client_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "test.sock")
+	server := NewUnixServer(socketPath)
+
+	// 返回请求中的 _meta，用于验证拦截器对请求的修改
+	server.RegisterHandler("meta", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return params["_meta"], nil
+	})
+
+	// 前两次调用失败，第三次成功
+	var flakyCalls int
+	server.RegisterHandler("flaky", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		flakyCalls++
+		if flakyCalls < 3 {
+			return nil, errors.New("temporarily unavailable")
+		}
+		return "ok", nil
+	})
+
+	server.RegisterHandler("invalid", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return nil, NewError(InvalidParams, "invalid params", nil)
+	})
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	var (
+		mu       sync.Mutex
+		sent     []string
+		received []map[string]interface{}
+	)
+	client, err := NewUnixClient(socketPath,
+		WithInterceptor(
+			// 记录每一次真正发出的请求
+			func(ctx context.Context, request *Request, invoker Invoker) (json.RawMessage, error) {
+				if request.Params == nil {
+					request.Params = map[string]interface{}{}
+				}
+				request.Params["_meta"] = map[string]interface{}{"traceId": "trace-1"}
+				return invoker(ctx, request)
+			},
+			// flaky 可以安全地重复执行，显式重试 InternalError
+			RetryInterceptor(RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, Retryable: func(err error) bool {
+				var rpcErr *Error
+				return errors.As(err, &rpcErr) && rpcErr.Code == InternalError
+			}}),
+			func(ctx context.Context, request *Request, invoker Invoker) (json.RawMessage, error) {
+				mu.Lock()
+				sent = append(sent, request.Method)
+				mu.Unlock()
+				return invoker(ctx, request)
+			},
+		),
+		WithMessageInterceptor(func(msg map[string]interface{}) {
+			mu.Lock()
+			received = append(received, msg)
+			mu.Unlock()
+		}),
+	)
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 拦截器写入的 _meta 应该被服务器收到
+	result, err := client.Call(ctx, "meta", nil)
+	if err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	if string(result) != `{"traceId":"trace-1"}` {
+		t.Errorf("响应中的result字段错误: 得到 %s", result)
+	}
+
+	// 暂时性错误会被重试
+	result, err = client.Call(ctx, "flaky", nil)
+	if err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	if string(result) != `"ok"` {
+		t.Errorf("响应中的result字段错误: 期望 \"ok\", 得到 %s", result)
+	}
+
+	// 服务器明确拒绝的请求不会被重试
+	_, err = client.Call(ctx, "invalid", nil)
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != InvalidParams {
+		t.Fatalf("应该返回 InvalidParams 错误, 得到 %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []string{"meta", "flaky", "flaky", "flaky", "invalid"}
+	if len(sent) != len(expected) {
+		t.Fatalf("发出的请求错误: 期望 %v, 得到 %v", expected, sent)
+	}
+	for i := range expected {
+		if sent[i] != expected[i] {
+			t.Errorf("发出的请求错误: 期望 %v, 得到 %v", expected, sent)
+			break
+		}
+	}
+	if len(received) != len(expected) {
+		t.Errorf("消息拦截器应该观察到 %d 条响应, 得到 %d", len(expected), len(received))
+	}
+}
+
+// 测试默认的重试条件 - 只重试没有发出的请求
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{&sendError{err: errors.New("connection refused")}, true},
+		{fmt.Errorf("call failed: %w", &sendError{err: errors.New("connection refused")}), true},
+		{&sendError{err: ErrClosed}, false},
+		{&sendError{err: context.DeadlineExceeded}, false},
+		{NewError(InternalError, "handler failed", nil), false},
+		{ErrTimeout, false},
+		{context.DeadlineExceeded, false},
+		{ErrClosed, false},
+	}
+	for _, tt := range tests {
+		if got := IsTransientError(tt.err); got != tt.expected {
+			t.Errorf("IsTransientError(%v): 期望 %v, 得到 %v", tt.err, tt.expected, got)
+		}
+	}
+}
+
+// 测试通知处理器中调用客户端 - 大量通知排队时仍然能够读取响应
+func TestUnixClient_NotificationHandlerCall(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "unix_client_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "test.sock")
+	server := NewUnixServer(socketPath)
+	server.RegisterHandler("echo", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return params["message"], nil
+	})
+	server.RegisterHandler("burst", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		session := SessionFromContext(ctx)
+		for i := 0; i < 200; i++ {
+			if err := session.Notify("notifications/progress", map[string]interface{}{"progress": i}); err != nil {
+				return nil, err
+			}
+		}
+		return "done", nil
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		client  Client
+		handled int
+	)
+	echoed := make(chan error, 1)
+	created, err := NewUnixClient(socketPath, WithNotificationHandler("notifications/progress", func(method string, params map[string]interface{}) {
+		mu.Lock()
+		c := client
+		handled++
+		first := handled == 1
+		mu.Unlock()
+		if first {
+			// 处理器等待响应期间，读取循环继续接收后续的通知
+			_, err := c.Call(ctx, "echo", map[string]interface{}{"message": "hi"})
+			echoed <- err
+		}
+	}))
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	defer created.Close()
+	mu.Lock()
+	client = created
+	mu.Unlock()
+
+	if _, err := created.Call(ctx, "burst", nil); err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	if err := <-echoed; err != nil {
+		t.Fatalf("通知处理器中调用失败: %v", err)
+	}
+}
@@ -0,0 +1,297 @@
+package gomcp
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID 用于计算握手响应中的 Sec-WebSocket-Accept（RFC 6455 第 1.3 节）
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// websocketMaxMessage 是单条消息允许的最大字节数
+const websocketMaxMessage = 16 * 1024 * 1024
+
+// WebSocket 帧的操作码
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// NewWebSocketClient 创建一个使用 WebSocket 传输的 MCP 客户端，每条 JSON-RPC 消息对应一个文本帧，
+// header 会附加到握手请求中。ctx 只用于建立连接与握手，连接建立后取消 ctx 不会关闭客户端
+func NewWebSocketClient(ctx context.Context, endpoint string, header http.Header, opts ...ClientOption) (Client, error) {
+	t, err := dialWebSocket(ctx, endpoint, header)
+	if err != nil {
+		return nil, err
+	}
+	return newRemoteClient(t, opts), nil
+}
+
+// websocketTransport 是 WebSocket 传输的客户端
+type websocketTransport struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	mu        sync.Mutex // 保护帧的并发写入
+	closeOnce sync.Once
+}
+
+// dialWebSocket 建立连接并完成 WebSocket 握手
+func dialWebSocket(ctx context.Context, endpoint string, header http.Header) (*websocketTransport, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server url: %w", err)
+	}
+
+	host := u.Host
+	var conn net.Conn
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+		dialer := &net.Dialer{Timeout: 30 * time.Second}
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	case "wss":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+		dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: 30 * time.Second}}
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	default:
+		return nil, fmt.Errorf("invalid server url: unsupported websocket scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+
+	// 握手期间 ctx 结束时关闭连接，让读写立即失败
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	t, err := handshakeWebSocket(conn, u, header)
+	if !stop() {
+		conn.Close()
+		return nil, fmt.Errorf("failed to connect to server: %w", ctx.Err())
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// handshakeWebSocket 发送升级请求并校验服务器的响应
+func handshakeWebSocket(conn net.Conn, u *url.URL, header http.Header) (*websocketTransport, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	httpURL := *u
+	httpURL.Scheme = strings.Replace(u.Scheme, "ws", "http", 1)
+	req, err := http.NewRequest(http.MethodGet, httpURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Protocol", "mcp")
+
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("failed to send websocket handshake: %w", err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read websocket handshake: %w", err)
+	}
+	resp.Body.Close()
+	conn.SetDeadline(time.Time{})
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("unexpected http status: %s", resp.Status)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		return nil, errors.New("invalid websocket handshake: accept key mismatch")
+	}
+	if protocol := resp.Header.Get("Sec-WebSocket-Protocol"); protocol != "mcp" {
+		return nil, fmt.Errorf("invalid websocket handshake: unexpected subprotocol %q", protocol)
+	}
+	return &websocketTransport{conn: conn, reader: reader}, nil
+}
+
+// websocketAccept 计算与 key 对应的 Sec-WebSocket-Accept
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// readMessage 读取一条完整的数据消息，期间自动响应 ping 与 close 帧
+func (t *websocketTransport) readMessage() ([]byte, error) {
+	var message []byte
+	for {
+		fin, opcode, payload, err := readWebSocketFrame(t.reader)
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case wsPing:
+			if err := t.writeFrame(wsPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			t.writeFrame(wsClose, payload)
+			return nil, io.EOF
+		case wsText, wsBinary, wsContinuation:
+		default:
+			return nil, fmt.Errorf("invalid websocket frame: unknown opcode %#x", opcode)
+		}
+
+		message = append(message, payload...)
+		if len(message) > websocketMaxMessage {
+			return nil, errors.New("websocket message too large")
+		}
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+func (t *websocketTransport) writeMessage(ctx context.Context, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return t.writeFrame(wsText, data)
+}
+
+func (t *websocketTransport) writeFrame(opcode byte, payload []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return writeWebSocketFrame(t.conn, opcode, payload, true)
+}
+
+// close 发送 close 帧后关闭连接
+func (t *websocketTransport) close() error {
+	var err error
+	t.closeOnce.Do(func() {
+		t.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		t.writeFrame(wsClose, nil)
+		err = t.conn.Close()
+	})
+	return err
+}
+
+// readWebSocketFrame 读取一个帧，并在帧带有掩码时还原负载
+func readWebSocketFrame(r io.Reader) (fin bool, opcode byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(r, head[:]); err != nil {
+		return
+	}
+	fin = head[0]&0x80 != 0
+	opcode = head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(r, ext[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(r, ext[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > websocketMaxMessage {
+		err = errors.New("websocket message too large")
+		return
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(r, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+// writeWebSocketFrame 写入一个不分片的帧，客户端发送的帧必须带掩码
+func writeWebSocketFrame(w io.Writer, opcode byte, payload []byte, masked bool) error {
+	frame := []byte{0x80 | opcode}
+
+	var maskBit byte
+	if masked {
+		maskBit = 0x80
+	}
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+
+	if !masked {
+		frame = append(frame, payload...)
+	} else {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := range payload {
+			frame[start+i] ^= mask[i%4]
+		}
+	}
+
+	_, err := w.Write(frame)
+	return err
+}
@@ -0,0 +1,149 @@
+package gomcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// maxCompletionValues 是一次补全最多返回的候选值数量
+const maxCompletionValues = 100
+
+// CompletionFunc 返回参数的候选值，value 为用户已经输入的部分，
+// arguments 为同一提示词或模板中已经填写的其他参数。
+// 候选值超过 100 个时服务器只返回前 100 个，并设置 hasMore
+type CompletionFunc func(ctx context.Context, value string, arguments map[string]string) ([]string, error)
+
+// PrefixCompletion 返回按前缀过滤固定候选值的 CompletionFunc
+func PrefixCompletion(candidates ...string) CompletionFunc {
+	return func(ctx context.Context, value string, arguments map[string]string) ([]string, error) {
+		values := make([]string, 0, len(candidates))
+		for _, candidate := range candidates {
+			if strings.HasPrefix(candidate, value) {
+				values = append(values, candidate)
+			}
+		}
+		return values, nil
+	}
+}
+
+// CompletionReference 指定要补全的对象，Type 为 ref/prompt（使用 Name）或 ref/resource（使用 URI 模板）
+type CompletionReference struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+	URI  string `json:"uri,omitempty"`
+}
+
+// PromptReference 创建指向提示词的补全引用
+func PromptReference(name string) CompletionReference {
+	return CompletionReference{Type: "ref/prompt", Name: name}
+}
+
+// ResourceReference 创建指向资源模板的补全引用
+func ResourceReference(uriTemplate string) CompletionReference {
+	return CompletionReference{Type: "ref/resource", URI: uriTemplate}
+}
+
+// CompletionArgument 是正在补全的参数及其当前值
+type CompletionArgument struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CompletionContext 是补全时已经填写的其他参数
+type CompletionContext struct {
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// CompleteRequest 是 completion/complete 请求的参数
+type CompleteRequest struct {
+	Ref      CompletionReference `json:"ref"`
+	Argument CompletionArgument  `json:"argument"`
+	Context  *CompletionContext  `json:"context,omitempty"`
+}
+
+// CompletionResult 是补全的结果，Total 为候选值总数，HasMore 表示还有未返回的候选值
+type CompletionResult struct {
+	Values  []string `json:"values"`
+	Total   int      `json:"total,omitempty"`
+	HasMore bool     `json:"hasMore,omitempty"`
+}
+
+// complete 处理 completion/complete 请求
+func (c *serverCore) complete(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	var request CompleteRequest
+	if err := remarshal(params, &request); err != nil {
+		return nil, NewError(InvalidParams, fmt.Sprintf("invalid completion request: %v", err), nil)
+	}
+
+	completion, err := c.completionFunc(request.Ref, request.Argument.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	result := CompletionResult{Values: []string{}}
+	if completion != nil {
+		var arguments map[string]string
+		if request.Context != nil {
+			arguments = request.Context.Arguments
+		}
+		values, err := completion(ctx, request.Argument.Value, arguments)
+		if err != nil {
+			return nil, err
+		}
+		result.Total = len(values)
+		if len(values) > maxCompletionValues {
+			values, result.HasMore = values[:maxCompletionValues], true
+		}
+		if values != nil {
+			result.Values = values
+		}
+	}
+	return map[string]interface{}{"completion": result}, nil
+}
+
+// completionFunc 查找引用对象中指定参数的 CompletionFunc，参数未设置补全时返回 nil
+func (c *serverCore) completionFunc(ref CompletionReference, argument string) (CompletionFunc, error) {
+	switch ref.Type {
+	case "ref/prompt":
+		entry, exists := c.prompt(ref.Name)
+		if !exists {
+			return nil, NewError(InvalidParams, fmt.Sprintf("Prompt not found: %s", ref.Name), nil)
+		}
+		arg, exists := entry.prompt.argument(argument)
+		if !exists {
+			return nil, NewError(InvalidParams, fmt.Sprintf("Unknown argument: %s", argument), nil)
+		}
+		return arg.Complete, nil
+	case "ref/resource":
+		entry, exists := c.resourceTemplate(ref.URI)
+		if !exists {
+			return nil, NewError(InvalidParams, fmt.Sprintf("Resource template not found: %s", ref.URI), nil)
+		}
+		return entry.template.Complete[argument], nil
+	default:
+		return nil, NewError(InvalidParams, fmt.Sprintf("invalid reference type: %s", ref.Type), nil)
+	}
+}
+
+// Complete 请求服务器补全提示词参数或资源模板变量
+func Complete(ctx context.Context, client Client, request CompleteRequest) (*CompletionResult, error) {
+	var params map[string]interface{}
+	if err := remarshal(request, &params); err != nil {
+		return nil, fmt.Errorf("failed to marshal completion request: %w", err)
+	}
+
+	raw, err := client.Call(ctx, "completion/complete", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Completion CompletionResult `json:"completion"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal completion result: %w", err)
+	}
+	return &result.Completion, nil
+}
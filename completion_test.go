@@ -0,0 +1,187 @@
+package gomcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 测试参数补全 - 提示词参数与资源模板变量
+func TestCompletion_Complete(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "completion_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "test.sock")
+	server := NewUnixServer(socketPath)
+	server.RegisterPrompt(Prompt{
+		Name: "review",
+		Arguments: []PromptArgument{
+			{Name: "repo", Required: true, Complete: PrefixCompletion("gomcp", "gofmt", "mcp-go")},
+			{Name: "note"},
+		},
+	}, func(ctx context.Context, arguments map[string]string) (*GetPromptResult, error) {
+		return &GetPromptResult{Messages: []PromptMessage{
+			{Role: "user", Content: TextContent("review " + arguments["repo"])},
+		}}, nil
+	})
+	server.RegisterResourceTemplate(ResourceTemplate{
+		URITemplate: "repo://{repo}/branches/{+branch}",
+		Name:        "branch",
+		Complete: map[string]CompletionFunc{
+			"branch": func(ctx context.Context, value string, arguments map[string]string) ([]string, error) {
+				var branches []string
+				for i := 0; i < 150; i++ {
+					branches = append(branches, fmt.Sprintf("%s/%s-%d", arguments["repo"], value, i))
+				}
+				return branches, nil
+			},
+		},
+	}, func(ctx context.Context, uri string, variables map[string]string) (*ReadResourceResult, error) {
+		return &ReadResourceResult{Contents: []ResourceContents{
+			{URI: uri, Text: variables["repo"] + "@" + variables["branch"]},
+		}}, nil
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	client, err := NewUnixClient(socketPath)
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 按前缀补全提示词参数
+	result, err := Complete(ctx, client, CompleteRequest{
+		Ref:      PromptReference("review"),
+		Argument: CompletionArgument{Name: "repo", Value: "go"},
+	})
+	if err != nil {
+		t.Fatalf("补全失败: %v", err)
+	}
+	if !reflect.DeepEqual(result.Values, []string{"gomcp", "gofmt"}) || result.Total != 2 || result.HasMore {
+		t.Errorf("补全结果错误: %+v", result)
+	}
+
+	// 未设置补全的参数返回空列表
+	result, err = Complete(ctx, client, CompleteRequest{
+		Ref:      PromptReference("review"),
+		Argument: CompletionArgument{Name: "note"},
+	})
+	if err != nil {
+		t.Fatalf("补全失败: %v", err)
+	}
+	if result.Values == nil || len(result.Values) != 0 {
+		t.Errorf("补全结果应该为空列表: %+v", result)
+	}
+
+	// 资源模板变量补全，超过 100 个候选值时截断
+	result, err = Complete(ctx, client, CompleteRequest{
+		Ref:      ResourceReference("repo://{repo}/branches/{+branch}"),
+		Argument: CompletionArgument{Name: "branch", Value: "feat"},
+		Context:  &CompletionContext{Arguments: map[string]string{"repo": "gomcp"}},
+	})
+	if err != nil {
+		t.Fatalf("补全失败: %v", err)
+	}
+	if len(result.Values) != 100 || result.Total != 150 || !result.HasMore {
+		t.Errorf("补全结果错误: 期望 100 个值, total 150, hasMore, 得到 %d 个值, total %d, hasMore %v",
+			len(result.Values), result.Total, result.HasMore)
+	}
+	if result.Values[0] != "gomcp/feat-0" {
+		t.Errorf("补全值错误: 期望 gomcp/feat-0, 得到 %s", result.Values[0])
+	}
+
+	// 引用不存在的提示词
+	_, err = Complete(ctx, client, CompleteRequest{
+		Ref:      PromptReference("missing"),
+		Argument: CompletionArgument{Name: "repo"},
+	})
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != InvalidParams {
+		t.Errorf("期望 InvalidParams 错误, 得到 %v", err)
+	}
+
+	// 通过模板读取资源
+	raw, err := client.Call(ctx, "resources/read", map[string]interface{}{"uri": "repo://gomcp/branches/feat/x"})
+	if err != nil {
+		t.Fatalf("读取资源失败: %v", err)
+	}
+	if string(raw) != `{"contents":[{"uri":"repo://gomcp/branches/feat/x","text":"gomcp@feat/x"}]}` {
+		t.Errorf("资源内容错误: %s", raw)
+	}
+
+	// 缺少必填参数
+	_, err = client.Call(ctx, "prompts/get", map[string]interface{}{"name": "review"})
+	if !errors.As(err, &rpcErr) || rpcErr.Code != InvalidParams {
+		t.Errorf("期望 InvalidParams 错误, 得到 %v", err)
+	}
+
+	raw, err = client.Call(ctx, "initialize", map[string]interface{}{"protocolVersion": LatestProtocolVersion})
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	var initResult struct {
+		Capabilities map[string]interface{} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(raw, &initResult); err != nil {
+		t.Fatalf("解析初始化结果失败: %v", err)
+	}
+	for _, capability := range []string{"prompts", "resources", "completions"} {
+		if _, ok := initResult.Capabilities[capability]; !ok {
+			t.Errorf("服务器应该声明 %s 能力: %v", capability, initResult.Capabilities)
+		}
+	}
+}
+
+// 测试补全能力 - 没有提示词与资源模板时同样声明
+func TestCompletion_Capability(t *testing.T) {
+	var core serverCore
+	if _, ok := core.capabilities()["completions"]; !ok {
+		t.Errorf("服务器应该总是声明 completions 能力: %v", core.capabilities())
+	}
+}
+
+// 测试 URI 模板匹配
+func TestURITemplate_Match(t *testing.T) {
+	tests := []struct {
+		template  string
+		uri       string
+		variables map[string]string
+	}{
+		{"file:///{name}.txt", "file:///notes.txt", map[string]string{"name": "notes"}},
+		{"file:///{name}.txt", "file:///a/notes.txt", nil},
+		{"repo://{repo}/tree/{+path}", "repo://gomcp/tree/a/b.go", map[string]string{"repo": "gomcp", "path": "a/b.go"}},
+		{"repo://{repo}", "repo://gomcp/x", nil},
+	}
+
+	for _, tt := range tests {
+		pattern, err := parseURITemplate(tt.template)
+		if err != nil {
+			t.Fatalf("解析模板 %s 失败: %v", tt.template, err)
+		}
+		variables, ok := pattern.match(tt.uri)
+		if ok != (tt.variables != nil) || !reflect.DeepEqual(variables, tt.variables) {
+			t.Errorf("%s 匹配 %s: 期望 %v, 得到 %v", tt.template, tt.uri, tt.variables, variables)
+		}
+	}
+
+	for _, template := range []string{"file:///{name", "file:///{}", "file:///{a,b}"} {
+		if _, err := parseURITemplate(template); err == nil {
+			t.Errorf("模板 %s 应该解析失败", template)
+		}
+	}
+}
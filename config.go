@@ -1,37 +1,191 @@
 package gomcp
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 // Config 表示 MCP 配置文件的结构
 type Config struct {
 	MCPServers map[string]ServerConfig `json:"mcpServers"`
+
+	path   string        // 加载配置的文件路径，用于 Validate 报告错误位置
+	source []byte        // 配置文件的原始内容
+	layers []loadedLayer // 分层加载时的各层，按优先级从低到高排列
 }
 
+// 服务器的传输类型
+const (
+	TransportStdio     = "stdio"     // 启动子进程，通过标准输入输出通信
+	TransportUnix      = "unix"      // 连接 Unix Domain Socket
+	TransportHTTP      = "http"      // Streamable HTTP
+	TransportSSE       = "sse"       // HTTP+SSE（2024-11-05 版本协议）
+	TransportWebSocket = "websocket" // WebSocket
+)
+
+// stdio 服务器标准错误输出的去向，其他取值视为文件路径，输出追加到该文件
+const (
+	StderrDiscard = "discard" // 丢弃，未设置时的默认行为
+	StderrInherit = "inherit" // 输出到当前进程的标准错误
+)
+
 // ServerConfig 表示单个 MCP 服务器的配置
 type ServerConfig struct {
-	Command  string            `json:"command"`
-	Args     []string          `json:"args"`
-	Env      map[string]string `json:"env,omitempty"`
-	Disabled bool              `json:"disabled,omitempty"`
+	Type           string            `json:"type,omitempty"`
+	Command        string            `json:"command,omitempty"`
+	Args           []string          `json:"args,omitempty"`
+	Env            map[string]string `json:"env,omitempty"`
+	Cwd            string            `json:"cwd,omitempty"`        // 子进程的工作目录，为空时使用当前工作目录
+	InheritEnv     *EnvInheritance   `json:"inheritEnv,omitempty"` // 子进程继承的环境变量，为空时全部继承
+	Stderr         string            `json:"stderr,omitempty"`     // 子进程标准错误输出的去向，见 StderrDiscard
+	URL            string            `json:"url,omitempty"`
+	SocketPath     string            `json:"socketPath,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	StartupTimeout Duration          `json:"startupTimeout,omitempty"` // 建立连接并完成握手的最长时间
+	RequestTimeout Duration          `json:"requestTimeout,omitempty"` // 单个请求的默认超时时间
+	Disabled       bool              `json:"disabled,omitempty"`
+}
+
+// Duration 是配置中的时间长度，JSON 中写作 "30s" 这样的字符串，或以毫秒为单位的数字
+type Duration time.Duration
+
+// MarshalJSON 实现 json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var millis float64
+	if err := json.Unmarshal(data, &millis); err == nil {
+		*d = Duration(millis * float64(time.Millisecond))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid duration %s: expected a string like \"30s\" or milliseconds", data)
+	}
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// EnvInheritance 决定 stdio 服务器的子进程继承当前进程的哪些环境变量，
+// JSON 中写作 "all"、"none"，或允许继承的变量名数组。Env 中的变量总是会被设置
+type EnvInheritance struct {
+	None  bool     // 不继承任何环境变量
+	Allow []string // 不为空时只继承这些环境变量
 }
 
-// LoadConfig 从文件加载 MCP 配置
-func LoadConfig(configPath string) (*Config, error) {
+// MarshalJSON 实现 json.Marshaler
+func (e EnvInheritance) MarshalJSON() ([]byte, error) {
+	switch {
+	case e.None:
+		return json.Marshal("none")
+	case len(e.Allow) > 0:
+		return json.Marshal(e.Allow)
+	}
+	return json.Marshal("all")
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler
+func (e *EnvInheritance) UnmarshalJSON(data []byte) error {
+	var allow []string
+	if err := json.Unmarshal(data, &allow); err == nil {
+		*e = EnvInheritance{Allow: allow, None: len(allow) == 0}
+		return nil
+	}
+	var mode string
+	if err := json.Unmarshal(data, &mode); err != nil {
+		return fmt.Errorf(`invalid inheritEnv %s: expected "all", "none" or an array of variable names`, data)
+	}
+	switch mode {
+	case "all":
+		*e = EnvInheritance{}
+	case "none":
+		*e = EnvInheritance{None: true}
+	default:
+		return fmt.Errorf(`invalid inheritEnv %q: expected "all", "none" or an array of variable names`, mode)
+	}
+	return nil
+}
+
+// environ 返回子进程应当继承的环境变量
+func (e *EnvInheritance) environ() []string {
+	switch {
+	case e == nil:
+		return os.Environ()
+	case e.None:
+		return []string{}
+	case len(e.Allow) > 0:
+		env := []string{}
+		for _, key := range e.Allow {
+			if value, ok := os.LookupEnv(key); ok {
+				env = append(env, key+"="+value)
+			}
+		}
+		return env
+	}
+	return os.Environ()
+}
+
+// TransportType 返回服务器的传输类型。未设置 type 时根据其他字段推断：
+// 设置了 command 为 stdio，设置了 socketPath 为 unix，ws:// 与 wss:// 地址为 websocket，其他地址为 http
+func (s *ServerConfig) TransportType() string {
+	if s.Type != "" {
+		return s.Type
+	}
+	switch {
+	case s.Command != "":
+		return TransportStdio
+	case s.SocketPath != "":
+		return TransportUnix
+	case strings.HasPrefix(s.URL, "ws://") || strings.HasPrefix(s.URL, "wss://"):
+		return TransportWebSocket
+	case s.URL != "":
+		return TransportHTTP
+	}
+	return TransportStdio
+}
+
+// header 将配置中的请求头转换为 http.Header
+func (s *ServerConfig) header() http.Header {
+	header := make(http.Header, len(s.Headers))
+	for k, v := range s.Headers {
+		header.Set(k, v)
+	}
+	return header
+}
+
+// LoadConfig 从文件加载 MCP 配置，并展开其中的环境变量与密钥引用，详见 Config.Expand
+func LoadConfig(configPath string, opts ...ConfigOption) (*Config, error) {
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
+	return parseConfig(configPath, data, opts...)
+}
 
-	var config Config
+// parseConfig 解析从 configPath 读取的配置内容并展开引用
+func parseConfig(configPath string, data []byte, opts ...ConfigOption) (*Config, error) {
+	config := Config{path: configPath, source: data}
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	if err := config.Expand(opts...); err != nil {
+		return nil, fmt.Errorf("failed to expand config file %s: %w", configPath, err)
+	}
 
 	return &config, nil
 }
@@ -48,27 +202,152 @@ func (c *Config) GetServerConfig(name string) (*ServerConfig, error) {
 	return &config, nil
 }
 
-// BuildServer 构建指定的 MCP 服务器
+// BuildServer 构建指定的 MCP 服务器。stderr 为 inherit 时命令的标准错误输出指向当前进程，
+// 为文件路径时 BuildServer 不会打开文件，cmd.Stderr 保持为空，需要写入文件时使用 Connect
 func (c *Config) BuildServer(name string) (*exec.Cmd, error) {
 	config, err := c.GetServerConfig(name)
 	if err != nil {
 		return nil, err
 	}
+	if transport := config.TransportType(); transport != TransportStdio {
+		return nil, fmt.Errorf("server %s uses %s transport, not a command", name, transport)
+	}
 
 	// 使用更直接的方式连接标准输入输出
 	cmd := exec.Command(config.Command, config.Args...)
+	cmd.Dir = config.Cwd
 
-	// 设置环境变量
-	if len(config.Env) > 0 {
-		cmd.Env = os.Environ()
+	// 设置环境变量，全部继承且没有额外变量时保持 cmd.Env 为空
+	if len(config.Env) > 0 || config.InheritEnv != nil {
+		cmd.Env = config.InheritEnv.environ()
 		for k, v := range config.Env {
 			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 		}
 	}
 
+	// 写入文件的标准错误输出由 Connect 打开文件，BuildServer 返回的命令只处理 inherit
+	if config.Stderr == StderrInherit {
+		cmd.Stderr = os.Stderr
+	}
+
 	return cmd, nil
 }
 
+// Connect 根据服务器的传输类型建立客户端并完成 MCP 握手。
+// stdio 服务器返回 *ProcessClient，Close 时会终止子进程并等待其退出；
+// unix 服务器返回 *UnixClient；http、sse 与 websocket 服务器返回 *RemoteClient。
+// ctx 只控制握手过程，服务器配置了 startupTimeout 时握手还受其限制，握手失败时连接会被关闭；
+// 配置了 requestTimeout 时作为客户端请求的默认超时时间
+func (c *Config) Connect(ctx context.Context, name string, opts ...ClientOption) (Client, error) {
+	return c.connect(ctx, name, nil, opts...)
+}
+
+// connect 建立客户端并完成握手，stderr 不为空时接收 stdio 服务器子进程的标准错误输出
+func (c *Config) connect(ctx context.Context, name string, stderr io.Writer, opts ...ClientOption) (Client, error) {
+	config, err := c.GetServerConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	if config.StartupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(config.StartupTimeout))
+		defer cancel()
+	}
+	if config.RequestTimeout > 0 {
+		// 服务器的配置比调用方传入的通用选项更具体，放在最后
+		opts = append(opts[:len(opts):len(opts)], WithRequestTimeout(time.Duration(config.RequestTimeout)))
+	}
+
+	var client Client
+	switch transport := config.TransportType(); transport {
+	case TransportStdio:
+		process, err := c.connectProcess(ctx, name, stderr, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return process, nil
+	case TransportUnix:
+		client, err = dialUnixClient(ctx, config.SocketPath, opts...)
+	case TransportHTTP:
+		client, err = NewHTTPClient(config.URL, config.header(), opts...)
+	case TransportSSE:
+		client, err = NewSSEClient(ctx, config.URL, config.header(), opts...)
+	case TransportWebSocket:
+		client, err = NewWebSocketClient(ctx, config.URL, config.header(), opts...)
+	default:
+		return nil, fmt.Errorf("server %s has unknown transport type: %s", name, transport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server %s: %w", name, err)
+	}
+
+	options := newClientOptions(opts)
+	if _, err := Initialize(ctx, client, options.clientInfo, options.capabilities()); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to initialize server %s: %w", name, err)
+	}
+	return client, nil
+}
+
+// connectProcess 启动服务器子进程并完成握手，子进程的标准错误输出同时写入 stderr 与配置的去向
+func (c *Config) connectProcess(ctx context.Context, name string, stderr io.Writer, opts ...ClientOption) (*ProcessClient, error) {
+	cmd, err := c.BuildServer(name)
+	if err != nil {
+		return nil, err
+	}
+
+	config := c.MCPServers[name]
+	var file *os.File
+	if config.Stderr != "" && config.Stderr != StderrInherit && config.Stderr != StderrDiscard {
+		file, err = os.OpenFile(config.Stderr, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open stderr file for server %s: %w", name, err)
+		}
+	}
+
+	var writers []io.Writer
+	if stderr != nil {
+		writers = append(writers, stderr)
+	}
+	if cmd.Stderr != nil {
+		writers = append(writers, cmd.Stderr)
+	}
+	if file != nil {
+		writers = append(writers, file)
+	}
+	switch len(writers) {
+	case 0:
+	case 1:
+		cmd.Stderr = writers[0]
+	default:
+		cmd.Stderr = io.MultiWriter(writers...)
+	}
+
+	client, err := startProcessClient(cmd, opts...)
+	if file != nil {
+		if err != nil {
+			file.Close()
+		} else {
+			go func() {
+				<-client.Exited()
+				file.Close()
+			}()
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start server %s: %w", name, err)
+	}
+
+	options := newClientOptions(opts)
+	result, err := Initialize(ctx, client, options.clientInfo, options.capabilities())
+	if err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to initialize server %s: %w", name, err)
+	}
+	client.initResult = result
+	return client, nil
+}
+
 // GetDefaultConfigPath 获取默认配置文件路径
 func GetDefaultConfigPath() string {
 	homeDir, err := os.UserHomeDir()
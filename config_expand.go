@@ -0,0 +1,248 @@
+package gomcp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// SecretResolver 解析 ${scheme:reference} 形式的引用，返回引用对应的值
+type SecretResolver interface {
+	Resolve(reference string) (string, error)
+}
+
+// SecretResolverFunc 将普通函数适配为 SecretResolver
+type SecretResolverFunc func(reference string) (string, error)
+
+// Resolve 实现 SecretResolver
+func (f SecretResolverFunc) Resolve(reference string) (string, error) {
+	return f(reference)
+}
+
+// fileSecret 读取文件内容作为值，去掉末尾的换行，例如 ${file:/run/secrets/token}
+func fileSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// ConfigOption 定义加载配置的选项
+type ConfigOption func(*configOptions)
+
+type configOptions struct {
+	resolvers map[string]SecretResolver
+	lookupEnv func(key string) (string, bool)
+}
+
+// WithSecretResolver 为 ${scheme:reference} 引用注册解析器，可以覆盖内置的 file 与 env 解析器
+func WithSecretResolver(scheme string, resolver SecretResolver) ConfigOption {
+	return func(o *configOptions) {
+		o.resolvers[scheme] = resolver
+	}
+}
+
+// WithLookupEnv 设置查找 ${VAR} 变量的函数，未设置时使用 os.LookupEnv
+func WithLookupEnv(lookup func(key string) (string, bool)) ConfigOption {
+	return func(o *configOptions) {
+		o.lookupEnv = lookup
+	}
+}
+
+func newConfigOptions(opts []ConfigOption) configOptions {
+	o := configOptions{
+		resolvers: map[string]SecretResolver{"file": SecretResolverFunc(fileSecret)},
+		lookupEnv: os.LookupEnv,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	// ${env:VAR} 与 ${VAR} 相同，兼容 VS Code 的写法
+	if _, exists := o.resolvers["env"]; !exists {
+		lookup := o.lookupEnv
+		o.resolvers["env"] = SecretResolverFunc(func(name string) (string, error) {
+			value, ok := lookup(name)
+			if !ok {
+				return "", fmt.Errorf("environment variable %s is not set", name)
+			}
+			return value, nil
+		})
+	}
+	return o
+}
+
+// Expand 展开服务器配置中 command、args、env、cwd、stderr、url、socketPath 与 headers 的引用：
+//   - ${VAR} 替换为环境变量的值，变量未定义时报错
+//   - ${VAR:-default} 在变量未定义或为空时使用 default
+//   - ${env:VAR} 与 ${VAR} 相同
+//   - ${scheme:reference} 交给对应的 SecretResolver 解析，例如 ${file:/run/secrets/token}
+//   - $$ 表示字面的 $
+//
+// 所有无法解析的引用会一起报告，出错时配置保持不变。
+// 已禁用的服务器不会被连接，其中无法解析的引用不视为错误，保留原样
+func (c *Config) Expand(opts ...ConfigOption) error {
+	options := newConfigOptions(opts)
+
+	names := make([]string, 0, len(c.MCPServers))
+	for name := range c.MCPServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	expanded := make(map[string]ServerConfig, len(c.MCPServers))
+	for _, name := range names {
+		server, err := c.MCPServers[name].expand(&options)
+		if err != nil && c.MCPServers[name].Disabled {
+			server = c.MCPServers[name]
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("server %s: %w", name, err))
+			continue
+		}
+		expanded[name] = server
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	c.MCPServers = expanded
+	return nil
+}
+
+// expand 返回展开引用后的服务器配置副本
+func (s ServerConfig) expand(o *configOptions) (ServerConfig, error) {
+	var errs []error
+	expand := func(field, value string) string {
+		result, err := o.expand(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+		return result
+	}
+
+	s.Command = expand("command", s.Command)
+	if s.Args != nil {
+		args := make([]string, len(s.Args))
+		for i, arg := range s.Args {
+			args[i] = expand(fmt.Sprintf("args[%d]", i), arg)
+		}
+		s.Args = args
+	}
+	s.Env = expandMap("env", s.Env, expand)
+	s.Cwd = expand("cwd", s.Cwd)
+	s.Stderr = expand("stderr", s.Stderr)
+	s.URL = expand("url", s.URL)
+	s.SocketPath = expand("socketPath", s.SocketPath)
+	s.Headers = expandMap("headers", s.Headers, expand)
+	return s, errors.Join(errs...)
+}
+
+// expandMap 按键的顺序展开 map 中的值，返回新的 map
+func expandMap(field string, values map[string]string, expand func(field, value string) string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make(map[string]string, len(values))
+	for _, k := range keys {
+		result[k] = expand(field+"."+k, values[k])
+	}
+	return result
+}
+
+// expand 展开一个字符串中的所有引用
+func (o *configOptions) expand(value string) (string, error) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(value, '$')
+		if i < 0 || i == len(value)-1 {
+			b.WriteString(value)
+			return b.String(), nil
+		}
+		b.WriteString(value[:i])
+
+		switch value[i+1] {
+		case '$':
+			b.WriteByte('$')
+			value = value[i+2:]
+		case '{':
+			end := strings.IndexByte(value[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated reference %q", value[i:])
+			}
+			resolved, err := o.resolve(value[i+2 : i+end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(resolved)
+			value = value[i+end+1:]
+		default:
+			b.WriteByte('$')
+			value = value[i+1:]
+		}
+	}
+}
+
+// resolve 解析 ${...} 中的一个引用
+func (o *configOptions) resolve(ref string) (string, error) {
+	name, rest, hasColon := strings.Cut(ref, ":")
+	if hasColon && strings.HasPrefix(rest, "-") {
+		// ${VAR:-default}
+		if !isEnvName(name) {
+			return "", fmt.Errorf("invalid variable name in ${%s}", ref)
+		}
+		if value, ok := o.lookupEnv(name); ok && value != "" {
+			return value, nil
+		}
+		return rest[1:], nil
+	}
+
+	if hasColon {
+		// ${scheme:reference}
+		resolver, exists := o.resolvers[name]
+		if !exists {
+			return "", fmt.Errorf("unknown secret source %q in ${%s}", name, ref)
+		}
+		value, err := resolver.Resolve(rest)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve ${%s}: %w", ref, err)
+		}
+		return value, nil
+	}
+
+	if !isEnvName(name) {
+		return "", fmt.Errorf("invalid variable name in ${%s}", ref)
+	}
+	value, ok := o.lookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// isEnvName 判断 name 是否为合法的环境变量名
+func isEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,479 @@
+package gomcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ConfigFormat 是 MCP 宿主配置文件的格式
+type ConfigFormat string
+
+const (
+	// FormatMCPServers 以 mcpServers 为顶层键，gomcp 自身以及 Claude Desktop、Claude Code、Cursor、Windsurf、Cline 等宿主使用
+	FormatMCPServers ConfigFormat = "mcpServers"
+	// FormatVSCode 以 servers 为顶层键，例如 VS Code 的 .vscode/mcp.json，也可以嵌套在 settings.json 的 mcp 键中
+	FormatVSCode ConfigFormat = "vscode"
+	// FormatZed 以 context_servers 为顶层键，例如 Zed 的 settings.json
+	FormatZed ConfigFormat = "zed"
+)
+
+// DetectConfigFormat 根据顶层的键判断配置文件的格式，支持带注释与尾随逗号的 JSON
+func DetectConfigFormat(data []byte) (ConfigFormat, error) {
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(stripJSONComments(data), &root); err != nil {
+		return "", fmt.Errorf("failed to parse config file: %w", err)
+	}
+	switch {
+	case root["mcpServers"] != nil:
+		return FormatMCPServers, nil
+	case root["servers"] != nil:
+		return FormatVSCode, nil
+	case root["context_servers"] != nil:
+		return FormatZed, nil
+	case root["mcp"] != nil:
+		var mcp map[string]json.RawMessage
+		if json.Unmarshal(root["mcp"], &mcp) == nil && mcp["servers"] != nil {
+			return FormatVSCode, nil
+		}
+	}
+	return "", errors.New("unknown config format: expected a mcpServers, servers or context_servers key")
+}
+
+// ImportConfig 将其他宿主格式的配置转换为 Config，format 为空时自动判断格式。
+// 目标格式不支持的字段会被忽略；${...} 引用保持原样，连接服务器前需要调用 Config.Expand。
+// VS Code 格式中只能由 VS Code 确定的变量（例如 ${workspaceFolder} 与 ${input:...}）无法展开，导入时报错
+func ImportConfig(data []byte, format ConfigFormat) (*Config, error) {
+	data = stripJSONComments(data)
+	if format == "" {
+		detected, err := DetectConfigFormat(data)
+		if err != nil {
+			return nil, err
+		}
+		format = detected
+	}
+
+	var servers map[string]importedServer
+	switch format {
+	case FormatMCPServers:
+		var root struct {
+			MCPServers map[string]importedServer `json:"mcpServers"`
+		}
+		if err := json.Unmarshal(data, &root); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+		servers = root.MCPServers
+	case FormatVSCode:
+		var root struct {
+			Servers map[string]importedServer `json:"servers"`
+			MCP     struct {
+				Servers map[string]importedServer `json:"servers"`
+			} `json:"mcp"`
+		}
+		if err := json.Unmarshal(data, &root); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+		servers = root.Servers
+		if servers == nil {
+			servers = root.MCP.Servers
+		}
+	case FormatZed:
+		var root struct {
+			ContextServers map[string]importedServer `json:"context_servers"`
+		}
+		if err := json.Unmarshal(data, &root); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+		servers = root.ContextServers
+	default:
+		return nil, fmt.Errorf("unknown config format: %s", format)
+	}
+
+	config := &Config{MCPServers: make(map[string]ServerConfig, len(servers))}
+	var errs []error
+	for name, imported := range servers {
+		server, err := imported.serverConfig()
+		if err == nil && format == FormatVSCode {
+			err = checkVSCodeVariables(server)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("server %s: %w", name, err))
+			continue
+		}
+		config.MCPServers[name] = server
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return config, nil
+}
+
+// vscodeVariables 是 VS Code 预定义的变量，它们的值只有 VS Code 知道
+var vscodeVariables = map[string]bool{
+	"workspaceFolder":         true,
+	"workspaceFolderBasename": true,
+	"workspaceRoot":           true,
+	"userHome":                true,
+	"cwd":                     true,
+	"file":                    true,
+	"fileDirname":             true,
+	"fileBasename":            true,
+	"relativeFile":            true,
+	"execPath":                true,
+	"pathSeparator":           true,
+}
+
+// vscodeSchemes 是 VS Code 中需要交互或读取编辑器状态的变量前缀
+var vscodeSchemes = []string{"input", "command", "config"}
+
+// checkVSCodeVariables 检查服务器配置中是否引用了只有 VS Code 能够确定的变量
+func checkVSCodeVariables(server ServerConfig) error {
+	type field struct {
+		name, value string
+	}
+	fields := []field{{"command", server.Command}, {"cwd", server.Cwd}, {"url", server.URL}}
+	for i, arg := range server.Args {
+		fields = append(fields, field{fmt.Sprintf("args[%d]", i), arg})
+	}
+	for _, values := range []struct {
+		name   string
+		values map[string]string
+	}{{"env", server.Env}, {"headers", server.Headers}} {
+		keys := make([]string, 0, len(values.values))
+		for k := range values.values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fields = append(fields, field{values.name + "." + k, values.values[k]})
+		}
+	}
+
+	var errs []error
+	for _, f := range fields {
+		for _, ref := range configReferences(f.value) {
+			scheme, _, hasColon := strings.Cut(ref, ":")
+			if (!hasColon && vscodeVariables[ref]) || (hasColon && slices.Contains(vscodeSchemes, scheme)) {
+				errs = append(errs, fmt.Errorf("%s: VS Code variable ${%s} cannot be expanded outside VS Code", f.name, ref))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// configReferences 返回字符串中所有 ${...} 引用的内容，跳过 $$ 转义
+func configReferences(value string) []string {
+	var refs []string
+	for {
+		i := strings.IndexByte(value, '$')
+		if i < 0 || i == len(value)-1 {
+			return refs
+		}
+		switch value[i+1] {
+		case '$':
+			value = value[i+2:]
+		case '{':
+			end := strings.IndexByte(value[i:], '}')
+			if end < 0 {
+				return refs
+			}
+			refs = append(refs, value[i+2:i+end])
+			value = value[i+end+1:]
+		default:
+			value = value[i+1:]
+		}
+	}
+}
+
+// ImportConfigFile 读取其他宿主的配置文件并转换为 Config，详见 ImportConfig
+func ImportConfigFile(path string, format ConfigFormat) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	config, err := ImportConfig(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// importedServer 兼容各宿主服务器配置的字段，同时包含 ServerConfig 的所有字段
+type importedServer struct {
+	Type           string            `json:"type"`
+	TransportType  string            `json:"transportType"` // Cline
+	Command        json.RawMessage   `json:"command"`       // 字符串，或 Zed 旧版本的 {path, args, env}
+	Args           []string          `json:"args"`
+	Env            map[string]string `json:"env"`
+	Cwd            string            `json:"cwd"`
+	InheritEnv     *EnvInheritance   `json:"inheritEnv"`
+	Stderr         string            `json:"stderr"`
+	URL            string            `json:"url"`
+	ServerURL      string            `json:"serverUrl"` // Windsurf
+	SocketPath     string            `json:"socketPath"`
+	Headers        map[string]string `json:"headers"`
+	StartupTimeout Duration          `json:"startupTimeout"`
+	RequestTimeout Duration          `json:"requestTimeout"`
+	Disabled       bool              `json:"disabled"`
+	Enabled        *bool             `json:"enabled"` // Zed 等宿主使用 enabled 而不是 disabled
+}
+
+// serverConfig 转换为 ServerConfig
+func (s *importedServer) serverConfig() (ServerConfig, error) {
+	server := ServerConfig{
+		Args:           s.Args,
+		Env:            s.Env,
+		Cwd:            s.Cwd,
+		InheritEnv:     s.InheritEnv,
+		Stderr:         s.Stderr,
+		URL:            s.URL,
+		SocketPath:     s.SocketPath,
+		Headers:        s.Headers,
+		StartupTimeout: s.StartupTimeout,
+		RequestTimeout: s.RequestTimeout,
+		Disabled:       s.Disabled || (s.Enabled != nil && !*s.Enabled),
+	}
+	if server.URL == "" {
+		server.URL = s.ServerURL
+	}
+
+	if len(s.Command) > 0 && string(s.Command) != "null" {
+		if err := json.Unmarshal(s.Command, &server.Command); err != nil {
+			var nested struct {
+				Path string            `json:"path"`
+				Args []string          `json:"args"`
+				Env  map[string]string `json:"env"`
+			}
+			if err := json.Unmarshal(s.Command, &nested); err != nil {
+				return ServerConfig{}, fmt.Errorf("invalid command: %s", s.Command)
+			}
+			server.Command = nested.Path
+			server.Args = append(nested.Args, server.Args...)
+			if server.Env == nil {
+				server.Env = nested.Env
+			}
+		}
+	}
+
+	transport := s.Type
+	if transport == "" {
+		transport = s.TransportType
+	}
+	switch strings.ToLower(transport) {
+	case "":
+	case "stdio":
+		server.Type = TransportStdio
+	case "http", "streamable-http", "streamablehttp", "streamable_http":
+		server.Type = TransportHTTP
+	case "sse":
+		server.Type = TransportSSE
+	case "ws", "websocket":
+		server.Type = TransportWebSocket
+	case "unix":
+		server.Type = TransportUnix
+	default:
+		return ServerConfig{}, fmt.Errorf("unsupported transport type %q", transport)
+	}
+	// 能够推断出的类型不写入，保持配置简洁
+	inferred := ServerConfig{Command: server.Command, URL: server.URL, SocketPath: server.SocketPath}
+	if inferred.TransportType() == server.Type {
+		server.Type = ""
+	}
+	return server, nil
+}
+
+// Export 将配置转换为指定宿主格式的 JSON。FormatMCPServers 保留所有字段；
+// 其他格式只写入目标宿主支持的字段，并省略已禁用的服务器，启用的服务器使用目标宿主不支持的传输类型
+// （unix 与 websocket）时返回错误。
+// 通过 LoadConfig 加载的配置已经展开了引用，需要保留 ${...} 引用时应当使用 ImportConfig 读取配置
+func (c *Config) Export(format ConfigFormat) ([]byte, error) {
+	var root any
+	switch format {
+	case FormatMCPServers:
+		root = Config{MCPServers: c.MCPServers}
+	case FormatVSCode, FormatZed:
+		servers, err := c.exportServers(format)
+		if err != nil {
+			return nil, err
+		}
+		if format == FormatVSCode {
+			root = map[string]any{"servers": servers}
+		} else {
+			root = map[string]any{"context_servers": servers}
+		}
+	default:
+		return nil, fmt.Errorf("unknown config format: %s", format)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(root); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteFile 将配置以指定格式写入文件。先写入同目录下的临时文件再重命名，避免其他进程读到不完整的内容
+func (c *Config) WriteFile(path string, format ConfigFormat) error {
+	data, err := c.Export(format)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if info, err := os.Stat(path); err == nil {
+		_ = os.Chmod(file.Name(), info.Mode().Perm())
+	} else {
+		_ = os.Chmod(file.Name(), 0644)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// exportServers 按目标宿主支持的字段导出启用的服务器
+func (c *Config) exportServers(format ConfigFormat) (map[string]map[string]any, error) {
+	names := make([]string, 0, len(c.MCPServers))
+	for name := range c.MCPServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	servers := make(map[string]map[string]any, len(names))
+	for _, name := range names {
+		server := c.MCPServers[name]
+		if server.Disabled {
+			continue
+		}
+		transport := server.TransportType()
+		if transport != TransportStdio && transport != TransportHTTP && transport != TransportSSE {
+			errs = append(errs, fmt.Errorf("server %s uses %s transport, which is not supported by %s configs", name, transport, format))
+			continue
+		}
+
+		entry := make(map[string]any)
+		set := func(key string, value any, present bool) {
+			if present {
+				entry[key] = value
+			}
+		}
+		if format == FormatVSCode {
+			entry["type"] = transport
+		}
+		set("command", server.Command, server.Command != "")
+		set("args", server.Args, len(server.Args) > 0)
+		set("env", server.Env, len(server.Env) > 0)
+		set("cwd", server.Cwd, server.Cwd != "" && format == FormatVSCode)
+		set("url", server.URL, server.URL != "")
+		set("headers", server.Headers, len(server.Headers) > 0)
+		if format == FormatZed && transport == TransportStdio {
+			entry["source"] = "custom"
+		}
+		servers[name] = entry
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return servers, nil
+}
+
+// stripJSONComments 去掉 JSONC 中的注释与尾随逗号，字符串中的内容保持不变
+func stripJSONComments(data []byte) []byte {
+	if !bytes.ContainsAny(data, "/,") {
+		return data
+	}
+
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out = append(out, c)
+			switch c {
+			case '\\':
+				if i+1 < len(data) {
+					i++
+					out = append(out, data[i])
+				}
+			case '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return out
+			}
+			// 保留换行，使解析错误的位置与原文件一致
+			out = append(out, bytes.Repeat([]byte{'\n'}, bytes.Count(data[i:i+2+end], []byte{'\n'}))...)
+			i += end + 3
+		case c == ',':
+			// 后面紧跟 } 或 ] 的逗号是尾随逗号，中间可以有空白与注释
+			j := skipJSONSpace(data, i+1)
+			if j < len(data) && (data[j] == '}' || data[j] == ']') {
+				continue
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// skipJSONSpace 返回从 i 开始跳过空白与注释后的第一个位置
+func skipJSONSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch {
+		case data[i] == ' ' || data[i] == '\t' || data[i] == '\r' || data[i] == '\n':
+			i++
+		case data[i] == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		case data[i] == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return len(data)
+			}
+			i += end + 4
+		default:
+			return i
+		}
+	}
+	return i
+}
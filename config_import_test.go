@@ -0,0 +1,213 @@
+package gomcp
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format ConfigFormat
+		want   map[string]ServerConfig
+	}{
+		{
+			name:   "mcpServers",
+			format: FormatMCPServers,
+			data: `{"mcpServers": {
+				"local": {"command": "npx", "args": ["-y", "server"], "env": {"TOKEN": "${TOKEN}"}, "alwaysAllow": ["read"]},
+				"remote": {"type": "streamable-http", "url": "https://example.com/mcp", "headers": {"Authorization": "Bearer ${TOKEN}"}},
+				"windsurf": {"serverUrl": "https://example.com/sse", "transportType": "sse"},
+				"socket": {"socketPath": "/tmp/mcp.sock", "requestTimeout": "5s", "disabled": true}
+			}}`,
+			want: map[string]ServerConfig{
+				"local":    {Command: "npx", Args: []string{"-y", "server"}, Env: map[string]string{"TOKEN": "${TOKEN}"}},
+				"remote":   {URL: "https://example.com/mcp", Headers: map[string]string{"Authorization": "Bearer ${TOKEN}"}},
+				"windsurf": {Type: TransportSSE, URL: "https://example.com/sse"},
+				"socket":   {SocketPath: "/tmp/mcp.sock", RequestTimeout: Duration(5 * time.Second), Disabled: true},
+			},
+		},
+		{
+			name: "vscode",
+			data: `{
+				// VS Code 的 mcp.json 允许注释与尾随逗号
+				"inputs": [{"type": "promptString", "id": "token", "password": true}],
+				"servers": {
+					"local": {"type": "stdio", "command": "node", "args": ["server.js"], "env": {"TOKEN": "${env:TOKEN}"},},
+					/* 远程服务器 */
+					"remote": {"type": "http", "url": "https://example.com/mcp"},
+					"legacy": {"type": "sse", "url": "https://example.com/sse"}, // 尾随逗号后面的注释
+				},
+				/* 结尾的注释 */
+			}`,
+			want: map[string]ServerConfig{
+				"local":  {Command: "node", Args: []string{"server.js"}, Env: map[string]string{"TOKEN": "${env:TOKEN}"}},
+				"remote": {URL: "https://example.com/mcp"},
+				"legacy": {Type: TransportSSE, URL: "https://example.com/sse"},
+			},
+		},
+		{
+			name: "vscode settings",
+			data: `{"editor.tabSize": 2, "mcp": {"servers": {"local": {"command": "node"}}}}`,
+			want: map[string]ServerConfig{"local": {Command: "node"}},
+		},
+		{
+			name: "zed",
+			data: `{"theme": "One Dark", "context_servers": {
+				"nested": {"command": {"path": "uvx", "args": ["server"], "env": {"DEBUG": "1"}}, "settings": {}},
+				"flat": {"source": "custom", "command": "node", "args": ["server.js"], "enabled": false},
+				"remote": {"url": "https://example.com/mcp", "headers": {"X-Key": "value"}}
+			}}`,
+			want: map[string]ServerConfig{
+				"nested": {Command: "uvx", Args: []string{"server"}, Env: map[string]string{"DEBUG": "1"}},
+				"flat":   {Command: "node", Args: []string{"server.js"}, Disabled: true},
+				"remote": {URL: "https://example.com/mcp", Headers: map[string]string{"X-Key": "value"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ImportConfig([]byte(tt.data), tt.format)
+			if err != nil {
+				t.Fatalf("Failed to import config: %v", err)
+			}
+			if !reflect.DeepEqual(config.MCPServers, tt.want) {
+				t.Errorf("Unexpected servers:\ngot  %+v\nwant %+v", config.MCPServers, tt.want)
+			}
+		})
+	}
+
+	// 无法识别的格式与传输类型
+	if _, err := ImportConfig([]byte(`{"tools": {}}`), ""); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+	if _, err := ImportConfig([]byte(`{"servers": {"x": {"type": "grpc", "url": "x"}}}`), ""); err == nil || !strings.Contains(err.Error(), "server x") {
+		t.Errorf("Expected error for unsupported transport, got %v", err)
+	}
+
+	// 只有 VS Code 能够确定的变量在导入时报错，$$ 转义与其他格式不受影响
+	_, err := ImportConfig([]byte(`{"servers": {"x": {"command": "node", "args": ["${workspaceFolder}/server.js", "$${userHome}"], "env": {"TOKEN": "${input:token}"}}}}`), "")
+	if err == nil || !strings.Contains(err.Error(), "args[0]: VS Code variable ${workspaceFolder}") || !strings.Contains(err.Error(), "env.TOKEN: VS Code variable ${input:token}") {
+		t.Errorf("Expected error for VS Code variables, got %v", err)
+	}
+	if strings.Contains(fmt.Sprint(err), "userHome") {
+		t.Errorf("Escaped reference should not be reported: %v", err)
+	}
+	if _, err := ImportConfig([]byte(`{"mcpServers": {"x": {"command": "node", "args": ["${workspaceFolder}"]}}}`), ""); err != nil {
+		t.Errorf("Unexpected error for mcpServers format: %v", err)
+	}
+}
+
+func TestConfig_Export(t *testing.T) {
+	config := &Config{MCPServers: map[string]ServerConfig{
+		"local":    {Command: "node", Args: []string{"server.js"}, Env: map[string]string{"TOKEN": "${TOKEN}"}, Cwd: "/srv"},
+		"remote":   {URL: "https://example.com/mcp", Headers: map[string]string{"Authorization": "Bearer ${TOKEN}"}},
+		"legacy":   {Type: TransportSSE, URL: "https://example.com/sse"},
+		"disabled": {Command: "node", Disabled: true},
+	}}
+
+	data, err := config.Export(FormatVSCode)
+	if err != nil {
+		t.Fatalf("Failed to export config: %v", err)
+	}
+	want := `{
+  "servers": {
+    "legacy": {
+      "type": "sse",
+      "url": "https://example.com/sse"
+    },
+    "local": {
+      "args": [
+        "server.js"
+      ],
+      "command": "node",
+      "cwd": "/srv",
+      "env": {
+        "TOKEN": "${TOKEN}"
+      },
+      "type": "stdio"
+    },
+    "remote": {
+      "headers": {
+        "Authorization": "Bearer ${TOKEN}"
+      },
+      "type": "http",
+      "url": "https://example.com/mcp"
+    }
+  }
+}
+`
+	if string(data) != want {
+		t.Errorf("Unexpected vscode export:\n%s", data)
+	}
+
+	// 各格式导出后再导入，除了目标格式不支持的字段外保持一致
+	for _, format := range []ConfigFormat{FormatMCPServers, FormatVSCode, FormatZed} {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := config.WriteFile(path, format); err != nil {
+			t.Fatalf("Failed to write %s config: %v", format, err)
+		}
+		imported, err := ImportConfigFile(path, "")
+		if err != nil {
+			t.Fatalf("Failed to import %s config: %v", format, err)
+		}
+
+		expected := make(map[string]ServerConfig)
+		for name, server := range config.MCPServers {
+			if format != FormatMCPServers {
+				if server.Disabled {
+					continue
+				}
+				if format == FormatZed {
+					// Zed 不支持 cwd，远程服务器也不写入传输类型
+					server.Cwd, server.Type = "", ""
+				}
+			}
+			expected[name] = server
+		}
+		if !reflect.DeepEqual(imported.MCPServers, expected) {
+			t.Errorf("Unexpected %s round trip:\ngot  %+v\nwant %+v", format, imported.MCPServers, expected)
+		}
+	}
+}
+
+func TestConfig_ExportUnsupportedTransport(t *testing.T) {
+	config := &Config{MCPServers: map[string]ServerConfig{
+		"local":    {Command: "node"},
+		"socket":   {SocketPath: "/tmp/mcp.sock"},
+		"ws":       {URL: "wss://example.com/mcp"},
+		"disabled": {SocketPath: "/tmp/mcp.sock", Disabled: true},
+	}}
+	for _, format := range []ConfigFormat{FormatVSCode, FormatZed} {
+		_, err := config.Export(format)
+		if err == nil || !strings.Contains(err.Error(), "server socket uses unix transport") || !strings.Contains(err.Error(), "server ws uses websocket transport") {
+			t.Errorf("Expected %s export error for unsupported transports, got %v", format, err)
+		}
+		if strings.Contains(fmt.Sprint(err), "disabled") {
+			t.Errorf("Disabled servers should be skipped: %v", err)
+		}
+	}
+	if _, err := config.Export(FormatMCPServers); err != nil {
+		t.Errorf("Unexpected error for mcpServers export: %v", err)
+	}
+}
+
+func TestConfig_ExpandEnvScheme(t *testing.T) {
+	t.Setenv("GOMCP_TEST_TOKEN", "secret")
+	config, err := ImportConfig([]byte(`{"servers": {"local": {"command": "node", "env": {"TOKEN": "${env:GOMCP_TEST_TOKEN}"}}}}`), "")
+	if err != nil {
+		t.Fatalf("Failed to import config: %v", err)
+	}
+	if err := config.Expand(); err != nil {
+		t.Fatalf("Failed to expand config: %v", err)
+	}
+	if got := config.MCPServers["local"].Env["TOKEN"]; got != "secret" {
+		t.Errorf("Unexpected env: %s", got)
+	}
+}
@@ -0,0 +1,211 @@
+package gomcp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 配置层的名称，按优先级从低到高排列
+const (
+	ConfigLayerXDG      = "xdg"      // 用户配置目录中的 gomcp/config.json，遵循 XDG_CONFIG_HOME
+	ConfigLayerHome     = "home"     // 用户主目录中的 .mcp-config.json
+	ConfigLayerProject  = "project"  // 从工作目录向上查找到的第一个 .mcp-config.json，不超出版本库根目录与用户主目录
+	ConfigLayerExplicit = "explicit" // 显式指定的配置文件
+)
+
+// projectConfigName 是项目级配置文件的名称
+const projectConfigName = ".mcp-config.json"
+
+// ConfigLayer 是分层配置中的一层
+type ConfigLayer struct {
+	Name string // 层的名称，例如 ConfigLayerProject
+	Path string // 配置文件路径
+}
+
+func (l ConfigLayer) String() string {
+	return fmt.Sprintf("%s (%s)", l.Path, l.Name)
+}
+
+// DiscoverConfig 查找存在的配置文件，按优先级从低到高返回：
+// 用户配置目录、用户主目录、从 workDir 向上查找到的项目配置，以及 explicitPath。
+// workDir 为空时使用当前工作目录；explicitPath 为空时忽略，不为空时文件必须存在
+func DiscoverConfig(workDir, explicitPath string) ([]ConfigLayer, error) {
+	var layers []ConfigLayer
+	seen := make(map[string]bool)
+	add := func(name, path string) {
+		abs, err := filepath.Abs(path)
+		if err != nil || seen[abs] {
+			return
+		}
+		seen[abs] = true
+		layers = append(layers, ConfigLayer{Name: name, Path: abs})
+	}
+
+	if dir, ok := xdgConfigHome(); ok {
+		if path := filepath.Join(dir, "gomcp", "config.json"); fileExists(path) {
+			add(ConfigLayerXDG, path)
+		}
+	}
+	if path := GetDefaultConfigPath(); filepath.IsAbs(path) && fileExists(path) {
+		add(ConfigLayerHome, path)
+	}
+
+	if workDir == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+		workDir = dir
+	}
+	if path, ok := findProjectConfig(workDir); ok {
+		add(ConfigLayerProject, path)
+	}
+
+	if explicitPath != "" {
+		if !fileExists(explicitPath) {
+			return nil, fmt.Errorf("config file not found: %s", explicitPath)
+		}
+		add(ConfigLayerExplicit, explicitPath)
+	}
+	return layers, nil
+}
+
+// xdgConfigHome 返回 XDG_CONFIG_HOME，未设置或不是绝对路径时使用 ~/.config。
+// 与 os.UserConfigDir 不同，macOS 与 Windows 上同样使用这个目录，与其他命令行工具保持一致
+func xdgConfigHome() (string, bool) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return dir, true
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(home, ".config"), true
+}
+
+// findProjectConfig 从 dir 开始逐级向上查找项目配置文件。查找在包含 .git 的版本库根目录处停止，
+// 并且不会进入用户主目录及其上级目录，避免读取到与项目无关的配置
+func findProjectConfig(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	home, _ := os.UserHomeDir()
+	if home != "" {
+		home, _ = filepath.Abs(home)
+	}
+	for {
+		if dir == home {
+			return "", false // 主目录中的配置属于 home 层
+		}
+		path := filepath.Join(dir, projectConfigName)
+		if fileExists(path) {
+			return path, true
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", false // 到达版本库根目录
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// loadedLayer 是已经加载的配置层
+type loadedLayer struct {
+	ConfigLayer
+	config *Config
+}
+
+// LoadLayeredConfig 依次加载各层配置并按服务器名称合并，后面的层整体覆盖前面层中同名的服务器，
+// 例如项目配置可以用 "disabled": true 关闭全局配置中的服务器。
+// 各层分别展开引用，Validate 会在各自的文件中定位错误，Provenance 报告每个服务器来自哪一层
+func LoadLayeredConfig(layers []ConfigLayer, opts ...ConfigOption) (*Config, error) {
+	merged := &Config{MCPServers: make(map[string]ServerConfig)}
+	for _, layer := range layers {
+		config, err := LoadConfig(layer.Path, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s config: %w", layer.Name, err)
+		}
+		for name, server := range config.MCPServers {
+			merged.MCPServers[name] = server
+		}
+		merged.layers = append(merged.layers, loadedLayer{ConfigLayer: layer, config: config})
+	}
+	return merged, nil
+}
+
+// ServerProvenance 描述一个服务器的配置来自哪一层
+type ServerProvenance struct {
+	Name       string
+	Layer      ConfigLayer   // 生效的配置所在的层，服务器不是从文件加载时为零值
+	Overridden []ConfigLayer // 同名配置被覆盖的层，按优先级从低到高排列
+}
+
+func (p ServerProvenance) String() string {
+	if p.Layer.Path == "" {
+		return p.Name + ": defined in code"
+	}
+	s := fmt.Sprintf("%s: %s", p.Name, p.Layer)
+	if len(p.Overridden) > 0 {
+		overridden := make([]string, len(p.Overridden))
+		for i, layer := range p.Overridden {
+			overridden[i] = layer.String()
+		}
+		s += ", overrides " + strings.Join(overridden, ", ")
+	}
+	return s
+}
+
+// Provenance 返回每个服务器配置的来源，按名称排序
+func (c *Config) Provenance() []ServerProvenance {
+	names := make([]string, 0, len(c.MCPServers))
+	for name := range c.MCPServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]ServerProvenance, 0, len(names))
+	for _, name := range names {
+		provenance := ServerProvenance{Name: name}
+		for _, layer := range c.layerConfigs() {
+			if _, exists := layer.config.MCPServers[name]; !exists || layer.Path == "" {
+				continue
+			}
+			if provenance.Layer.Path != "" {
+				provenance.Overridden = append(provenance.Overridden, provenance.Layer)
+			}
+			provenance.Layer = layer.ConfigLayer
+		}
+		result = append(result, provenance)
+	}
+	return result
+}
+
+// ProvenanceReport 返回可读的来源报告，每个服务器一行
+func (c *Config) ProvenanceReport() string {
+	var b strings.Builder
+	for _, provenance := range c.Provenance() {
+		b.WriteString(provenance.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// layerConfigs 返回配置的各层；不是分层加载的配置只有自身一层
+func (c *Config) layerConfigs() []loadedLayer {
+	if len(c.layers) > 0 {
+		return c.layers
+	}
+	return []loadedLayer{{ConfigLayer: ConfigLayer{Name: ConfigLayerExplicit, Path: c.path}, config: c}}
+}
@@ -1,10 +1,15 @@
 package gomcp
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -112,3 +117,552 @@ func TestBuildServer(t *testing.T) {
 		t.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+func TestConfigConnect(t *testing.T) {
+	config := &Config{
+		MCPServers: map[string]ServerConfig{
+			"helper": helperServerConfig(""),
+			"missing": {
+				Command: filepath.Join(t.TempDir(), "missing-command"),
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := config.Connect(ctx, "helper", WithClientInfo("test-host", "1.0"))
+	if err != nil {
+		t.Fatalf("Failed to connect server: %v", err)
+	}
+
+	// 握手结果
+	process := client.(*ProcessClient)
+	result := process.InitializeResult()
+	if result.ServerInfo.Name != "helper" || result.ProtocolVersion != LatestProtocolVersion {
+		t.Errorf("Unexpected initialize result: %+v", result)
+	}
+
+	tool, err := CallTool(ctx, client, "echo", map[string]interface{}{"text": "hello"})
+	if err != nil {
+		t.Fatalf("Failed to call tool: %v", err)
+	}
+	if tool.Content[0].Text != "hello" {
+		t.Errorf("Unexpected tool result: %+v", tool)
+	}
+
+	// 关闭客户端后子进程退出
+	if err := client.Close(); err != nil {
+		t.Errorf("Failed to close client: %v", err)
+	}
+	select {
+	case <-process.Exited():
+	default:
+		t.Error("Server process should exit after Close")
+	}
+
+	// 命令不存在
+	if _, err := config.Connect(ctx, "missing"); err == nil {
+		t.Error("Expected error for missing command")
+	}
+}
+
+func TestServerConfig_TransportType(t *testing.T) {
+	tests := []struct {
+		config ServerConfig
+		want   string
+	}{
+		{ServerConfig{Command: "node"}, TransportStdio},
+		{ServerConfig{SocketPath: "/tmp/mcp.sock"}, TransportUnix},
+		{ServerConfig{URL: "https://example.com/mcp"}, TransportHTTP},
+		{ServerConfig{URL: "wss://example.com/mcp"}, TransportWebSocket},
+		{ServerConfig{Type: TransportSSE, URL: "https://example.com/sse"}, TransportSSE},
+	}
+	for _, tt := range tests {
+		if got := tt.config.TransportType(); got != tt.want {
+			t.Errorf("TransportType(%+v) = %s, want %s", tt.config, got, tt.want)
+		}
+	}
+
+	// 远程服务器没有可以构建的命令
+	config := &Config{MCPServers: map[string]ServerConfig{
+		"remote": {URL: "https://example.com/mcp"},
+	}}
+	if _, err := config.BuildServer("remote"); err == nil {
+		t.Error("Expected error when building a remote server")
+	}
+}
+
+func TestLoadConfig_Expand(t *testing.T) {
+	tmpDir := t.TempDir()
+	secretPath := filepath.Join(tmpDir, "token")
+	if err := os.WriteFile(secretPath, []byte("file-secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+	t.Setenv("GOMCP_TEST_BIN", "/usr/bin")
+	t.Setenv("GOMCP_TEST_EMPTY", "")
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	data := `{
+		"mcpServers": {
+			"local": {
+				"command": "${GOMCP_TEST_BIN}/node",
+				"args": ["--port=${GOMCP_TEST_PORT:-8080}", "${GOMCP_TEST_EMPTY:-fallback}", "$$HOME", "$HOME"],
+				"env": {"TOKEN": "${file:` + secretPath + `}", "VAULT": "${vault:github/token}"}
+			},
+			"remote": {
+				"url": "https://${GOMCP_TEST_HOST:-example.com}/mcp",
+				"headers": {"Authorization": "Bearer ${vault:remote}"}
+			}
+		}
+	}`
+	if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	// 注入的密钥解析器
+	vault := SecretResolverFunc(func(reference string) (string, error) {
+		return "vault:" + reference, nil
+	})
+	config, err := LoadConfig(configPath, WithSecretResolver("vault", vault))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	local := config.MCPServers["local"]
+	if local.Command != "/usr/bin/node" {
+		t.Errorf("Unexpected command: %v", local.Command)
+	}
+	wantArgs := []string{"--port=8080", "fallback", "$HOME", "$HOME"}
+	for i, want := range wantArgs {
+		if local.Args[i] != want {
+			t.Errorf("Unexpected args[%d]: got %v, want %v", i, local.Args[i], want)
+		}
+	}
+	if local.Env["TOKEN"] != "file-secret" || local.Env["VAULT"] != "vault:github/token" {
+		t.Errorf("Unexpected env: %v", local.Env)
+	}
+	remote := config.MCPServers["remote"]
+	if remote.URL != "https://example.com/mcp" || remote.Headers["Authorization"] != "Bearer vault:remote" {
+		t.Errorf("Unexpected remote config: %+v", remote)
+	}
+
+	// 未注册的解析器与未定义的变量在加载时一起报告
+	if _, err := LoadConfig(configPath, WithLookupEnv(func(string) (string, bool) { return "", false })); err == nil {
+		t.Fatal("Expected error for unresolved references")
+	} else {
+		for _, want := range []string{"server local: command: environment variable GOMCP_TEST_BIN is not set", `unknown secret source "vault"`} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Error %q should contain %q", err, want)
+			}
+		}
+	}
+}
+
+func TestConfig_ExpandErrors(t *testing.T) {
+	tests := []string{
+		"${UNTERMINATED",
+		"${1INVALID}",
+		"${file:/nonexistent/secret}",
+	}
+	for _, value := range tests {
+		config := &Config{MCPServers: map[string]ServerConfig{
+			"server": {Command: value},
+		}}
+		if err := config.Expand(); err == nil {
+			t.Errorf("Expected error for %q", value)
+		} else if config.MCPServers["server"].Command != value {
+			t.Errorf("Config should be unchanged after a failed expansion")
+		}
+	}
+
+	// 已禁用的服务器中无法解析的引用保留原样
+	config := &Config{MCPServers: map[string]ServerConfig{
+		"disabled": {Command: "${GOMCP_TEST_UNSET}/node", Disabled: true},
+		"enabled":  {Command: "${GOMCP_TEST_SET}/node"},
+	}}
+	lookup := WithLookupEnv(func(key string) (string, bool) { return "/usr/bin", key == "GOMCP_TEST_SET" })
+	if err := config.Expand(lookup); err != nil {
+		t.Fatalf("Unresolved references in a disabled server should not fail: %v", err)
+	}
+	if got := config.MCPServers["disabled"].Command; got != "${GOMCP_TEST_UNSET}/node" {
+		t.Errorf("Disabled server should be unchanged, got %v", got)
+	}
+	if got := config.MCPServers["enabled"].Command; got != "/usr/bin/node" {
+		t.Errorf("Enabled server should be expanded, got %v", got)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	data := `{
+  "mcpServers": {
+    "typo": {
+      "comand": "echo"
+    },
+    "local": {
+      "command": "gomcp-missing-executable",
+      "url": "https://example.com/mcp"
+    },
+    "remote": {
+      "type": "websocket",
+      "url": "https://example.com/mcp"
+    },
+    "local": {
+      "command": "echo"
+    },
+    "unknown": {
+      "type": "grpc"
+    },
+    "disabled": {
+      "command": "gomcp-missing-executable",
+      "cwd": "/gomcp-missing-dir",
+      "disabled": true
+    }
+  }
+}`
+	if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	err = config.Validate()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+
+	// 重复的服务器以最后一次出现为准，因此 local 的 command 与 url 不再报错
+	want := []ValidationError{
+		{Pointer: "/mcpServers/typo", Line: 3, Column: 5, Message: `missing required field "command" for stdio server`},
+		{Pointer: "/mcpServers/typo/comand", Line: 4, Column: 7, Message: `unknown key "comand", did you mean "command"?`},
+		{Pointer: "/mcpServers/remote/url", Line: 12, Column: 7, Message: `invalid url "https://example.com/mcp" for websocket server, expected a ws:// or wss:// url`},
+		{Pointer: "/mcpServers/local", Line: 14, Column: 5, Message: `duplicate server name "local"`},
+		{Pointer: "/mcpServers/unknown/type", Line: 18, Column: 7, Message: `unknown transport type "grpc", expected one of stdio, unix, http, sse, websocket`},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Expected %d errors, got %d:\n%v", len(want), len(errs), err)
+	}
+	for i, w := range want {
+		got := errs[i]
+		if got.Pointer != w.Pointer || got.Line != w.Line || got.Column != w.Column || got.Message != w.Message {
+			t.Errorf("Error %d: got %s:%d:%d %s, want %s:%d:%d %s", i, got.Pointer, got.Line, got.Column, got.Message, w.Pointer, w.Line, w.Column, w.Message)
+		}
+	}
+	if !strings.HasPrefix(errs[0].Error(), configPath+":3:5: /mcpServers/typo: ") {
+		t.Errorf("Unexpected error format: %s", errs[0])
+	}
+
+	// 在代码中构建的配置同样检查字段，但没有位置信息
+	config = &Config{MCPServers: map[string]ServerConfig{
+		"local":  {Command: "gomcp-missing-executable"},
+		"socket": {SocketPath: "/tmp/mcp.sock", Args: []string{"x"}},
+		"ok":     {Command: "echo"},
+		"off":    {Command: "echo", Cwd: "/gomcp-missing-dir", Disabled: true},
+	}}
+	err = config.Validate()
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %v", err)
+	}
+	if errs[0].Pointer != "/mcpServers/local/command" || errs[0].Line != 0 {
+		t.Errorf("Unexpected error: %+v", errs[0])
+	}
+	if errs[1].Pointer != "/mcpServers/socket/args" {
+		t.Errorf("Unexpected error: %+v", errs[1])
+	}
+
+	valid := &Config{MCPServers: map[string]ServerConfig{"ok": {Command: "echo"}}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid config, got %v", err)
+	}
+}
+
+func TestLoadLayeredConfig(t *testing.T) {
+	root := t.TempDir()
+	home := filepath.Join(root, "home")
+	xdg := filepath.Join(root, "xdg")
+	project := filepath.Join(root, "project")
+	workDir := filepath.Join(project, "src", "pkg")
+	for _, dir := range []string{home, filepath.Join(xdg, "gomcp"), workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+
+	write := func(path, data string) string {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		return path
+	}
+	xdgPath := write(filepath.Join(xdg, "gomcp", "config.json"), `{"mcpServers": {"a": {"command": "echo", "args": ["xdg"]}, "b": {"command": "echo"}}}`)
+	homePath := write(filepath.Join(home, ".mcp-config.json"), `{"mcpServers": {"b": {"command": "echo", "args": ["home"]}, "c": {"command": "echo"}}}`)
+	projectPath := write(filepath.Join(project, ".mcp-config.json"), `{"mcpServers": {"c": {"command": "echo", "disabled": true}}}`)
+	explicitPath := write(filepath.Join(root, "explicit.json"), `{"mcpServers": {
+  "a": {"command": "echo", "args": ["explicit"]},
+  "d": {"comand": "echo"}
+}}`)
+
+	layers, err := DiscoverConfig(workDir, explicitPath)
+	if err != nil {
+		t.Fatalf("Failed to discover config: %v", err)
+	}
+	wantLayers := []ConfigLayer{
+		{ConfigLayerXDG, xdgPath},
+		{ConfigLayerHome, homePath},
+		{ConfigLayerProject, projectPath},
+		{ConfigLayerExplicit, explicitPath},
+	}
+	if len(layers) != len(wantLayers) {
+		t.Fatalf("Unexpected layers: %v", layers)
+	}
+	for i, want := range wantLayers {
+		if layers[i] != want {
+			t.Errorf("Layer %d: got %v, want %v", i, layers[i], want)
+		}
+	}
+
+	config, err := LoadLayeredConfig(layers)
+	if err != nil {
+		t.Fatalf("Failed to load layered config: %v", err)
+	}
+
+	// 后面的层按服务器名称覆盖前面的层
+	if args := config.MCPServers["a"].Args; len(args) != 1 || args[0] != "explicit" {
+		t.Errorf("Unexpected args for a: %v", args)
+	}
+	if args := config.MCPServers["b"].Args; len(args) != 1 || args[0] != "home" {
+		t.Errorf("Unexpected args for b: %v", args)
+	}
+	if !config.MCPServers["c"].Disabled {
+		t.Error("Server c should be disabled by the project layer")
+	}
+
+	report := config.ProvenanceReport()
+	wantReport := "a: " + explicitPath + " (explicit), overrides " + xdgPath + " (xdg)\n" +
+		"b: " + homePath + " (home), overrides " + xdgPath + " (xdg)\n" +
+		"c: " + projectPath + " (project), overrides " + homePath + " (home)\n" +
+		"d: " + explicitPath + " (explicit)\n"
+	if report != wantReport {
+		t.Errorf("Unexpected provenance report:\n%s\nwant:\n%s", report, wantReport)
+	}
+
+	// 校验错误定位到服务器所在的文件
+	err = config.Validate()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Expected 2 validation errors, got %v", err)
+	}
+	for _, e := range errs {
+		if e.Path != explicitPath || e.Line != 3 {
+			t.Errorf("Unexpected error location: %s", e)
+		}
+	}
+
+	// 不带显式路径时只加载发现的配置
+	layers, err = DiscoverConfig(workDir, "")
+	if err != nil || len(layers) != 3 {
+		t.Errorf("Unexpected layers without explicit path: %v, %v", layers, err)
+	}
+	if _, err := DiscoverConfig(workDir, filepath.Join(root, "missing.json")); err == nil {
+		t.Error("Expected error for missing explicit config")
+	}
+}
+
+func TestDiscoverConfig_ProjectBoundary(t *testing.T) {
+	root := t.TempDir()
+	home := filepath.Join(root, "home")
+	repo := filepath.Join(home, "work", "repo")
+	workDir := filepath.Join(repo, "src")
+	for _, dir := range []string{workDir, filepath.Join(repo, ".git")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+
+	// 版本库根目录之外的配置不属于项目
+	outside := filepath.Join(home, "work", ".mcp-config.json")
+	if err := os.WriteFile(outside, []byte(`{"mcpServers": {}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	layers, err := DiscoverConfig(workDir, "")
+	if err != nil {
+		t.Fatalf("Failed to discover config: %v", err)
+	}
+	if len(layers) != 0 {
+		t.Errorf("Expected no layers outside the repository, got %v", layers)
+	}
+
+	// 没有版本库时在用户主目录之下停止，主目录的配置只作为 home 层
+	if err := os.RemoveAll(filepath.Join(repo, ".git")); err != nil {
+		t.Fatalf("Failed to remove .git: %v", err)
+	}
+	if err := os.Remove(outside); err != nil {
+		t.Fatalf("Failed to remove config file: %v", err)
+	}
+	homePath := filepath.Join(home, ".mcp-config.json")
+	if err := os.WriteFile(homePath, []byte(`{"mcpServers": {}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".mcp-config.json"), []byte(`{"mcpServers": {}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	layers, err = DiscoverConfig(workDir, "")
+	if err != nil {
+		t.Fatalf("Failed to discover config: %v", err)
+	}
+	if want := []ConfigLayer{{ConfigLayerHome, homePath}}; !slices.Equal(layers, want) {
+		t.Errorf("Unexpected layers: got %v, want %v", layers, want)
+	}
+}
+
+func TestDiscoverConfig_XDGFallback(t *testing.T) {
+	home := t.TempDir()
+	path := filepath.Join(home, ".config", "gomcp", "config.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(`{"mcpServers": {}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	// 未设置或设置为相对路径时使用 ~/.config
+	for _, xdg := range []string{"", "relative"} {
+		t.Setenv("XDG_CONFIG_HOME", xdg)
+		layers, err := DiscoverConfig(home, "")
+		if err != nil {
+			t.Fatalf("Failed to discover config: %v", err)
+		}
+		want := []ConfigLayer{{ConfigLayerXDG, path}}
+		if !slices.Equal(layers, want) {
+			t.Errorf("XDG_CONFIG_HOME=%q: got %v, want %v", xdg, layers, want)
+		}
+	}
+}
+
+func TestServerConfig_ProcessOptions(t *testing.T) {
+	var config Config
+	data := `{"mcpServers": {
+		"none": {"command": "echo", "inheritEnv": "none", "startupTimeout": "2s", "requestTimeout": 500},
+		"allow": {"command": "echo", "inheritEnv": ["GOMCP_TEST_ALLOWED"], "cwd": "/tmp", "stderr": "inherit"},
+		"all": {"command": "echo", "inheritEnv": "all"}
+	}}`
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	none := config.MCPServers["none"]
+	if !none.InheritEnv.None || time.Duration(none.StartupTimeout) != 2*time.Second || time.Duration(none.RequestTimeout) != 500*time.Millisecond {
+		t.Errorf("Unexpected options: %+v", none)
+	}
+	encoded, err := json.Marshal(none)
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+	if want := `{"command":"echo","inheritEnv":"none","startupTimeout":"2s","requestTimeout":"500ms"}`; string(encoded) != want {
+		t.Errorf("Unexpected json: got %s, want %s", encoded, want)
+	}
+
+	// 环境变量的继承
+	t.Setenv("GOMCP_TEST_ALLOWED", "allowed")
+	t.Setenv("GOMCP_TEST_SECRET", "secret")
+	cmd, err := config.BuildServer("none")
+	if err != nil {
+		t.Fatalf("Failed to build server: %v", err)
+	}
+	if cmd.Env == nil || len(cmd.Env) != 0 {
+		t.Errorf("Expected empty env, got %v", cmd.Env)
+	}
+	cmd, err = config.BuildServer("allow")
+	if err != nil {
+		t.Fatalf("Failed to build server: %v", err)
+	}
+	if len(cmd.Env) != 1 || cmd.Env[0] != "GOMCP_TEST_ALLOWED=allowed" || cmd.Dir != "/tmp" || cmd.Stderr != os.Stderr {
+		t.Errorf("Unexpected command: env %v, dir %s, stderr %v", cmd.Env, cmd.Dir, cmd.Stderr)
+	}
+	cmd, err = config.BuildServer("all")
+	if err != nil {
+		t.Fatalf("Failed to build server: %v", err)
+	}
+	if !slices.Contains(cmd.Env, "GOMCP_TEST_SECRET=secret") {
+		t.Errorf("Expected inherited env, got %d variables", len(cmd.Env))
+	}
+
+	for _, invalid := range []string{`{"inheritEnv": "some"}`, `{"startupTimeout": "soon"}`, `{"requestTimeout": true}`} {
+		var server ServerConfig
+		if err := json.Unmarshal([]byte(invalid), &server); err == nil {
+			t.Errorf("Expected error for %s", invalid)
+		}
+	}
+}
+
+func TestConfigConnect_ProcessOptions(t *testing.T) {
+	t.Setenv("GOMCP_TEST_SECRET", "secret")
+	dir := t.TempDir()
+	stderrPath := filepath.Join(dir, "stderr.log")
+
+	server := helperServerConfig("")
+	server.Cwd = dir
+	server.InheritEnv = &EnvInheritance{None: true}
+	server.Stderr = stderrPath
+	server.RequestTimeout = Duration(100 * time.Millisecond)
+	config := &Config{MCPServers: map[string]ServerConfig{"helper": server}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := config.Connect(ctx, "helper")
+	if err != nil {
+		t.Fatalf("Failed to connect server: %v", err)
+	}
+
+	text := func(name string, args map[string]interface{}) string {
+		t.Helper()
+		result, err := CallTool(ctx, client, name, args)
+		if err != nil {
+			t.Fatalf("Failed to call %s: %v", name, err)
+		}
+		return result.Content[0].Text
+	}
+	if got := text("cwd", nil); got != dir {
+		t.Errorf("Unexpected working directory: got %s, want %s", got, dir)
+	}
+	if got := text("env", map[string]interface{}{"name": "GOMCP_TEST_SECRET"}); got != "" {
+		t.Errorf("Environment should not be inherited, got %q", got)
+	}
+
+	// 超过 requestTimeout 的请求
+	if _, err := CallTool(ctx, client, "sleep", map[string]interface{}{"ms": 1000}); err == nil {
+		t.Error("Expected request timeout")
+	}
+
+	if err := client.Close(); err != nil {
+		t.Errorf("Failed to close client: %v", err)
+	}
+	data, err := os.ReadFile(stderrPath)
+	if err != nil || !strings.Contains(string(data), "helper started") {
+		t.Errorf("Unexpected stderr file: %q, %v", data, err)
+	}
+
+	// 启动超时
+	config.MCPServers["slow"] = ServerConfig{
+		Command:        "sleep",
+		Args:           []string{"10"},
+		StartupTimeout: Duration(100 * time.Millisecond),
+	}
+	start := time.Now()
+	if _, err := config.Connect(context.Background(), "slow"); err == nil {
+		t.Error("Expected startup timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Startup timeout took too long: %v", elapsed)
+	}
+}
@@ -0,0 +1,392 @@
+package gomcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationError 描述配置中的一个问题
+type ValidationError struct {
+	Path    string // 配置文件路径，配置不是从文件加载时为空
+	Pointer string // 出错位置的 JSON Pointer（RFC 6901），例如 /mcpServers/github/command
+	Line    int    // 出错位置的行号，从 1 开始，无法定位时为 0
+	Column  int    // 出错位置的列号，从 1 开始，无法定位时为 0
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	if e.Path != "" {
+		b.WriteString(e.Path)
+		b.WriteByte(':')
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d:", e.Line, e.Column)
+	}
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	fmt.Fprintf(&b, "%s: %s", e.Pointer, e.Message)
+	return b.String()
+}
+
+// ValidationErrors 是 Validate 发现的所有问题，按配置层与在文件中的位置排序
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// configKeys 是配置顶层允许出现的键
+var configKeys = map[string]bool{"mcpServers": true, "$schema": true}
+
+// serverConfigKeys 是服务器配置允许出现的键，来自 ServerConfig 的 json 标签
+var serverConfigKeys = jsonFieldNames(reflect.TypeOf(ServerConfig{}))
+
+// jsonFieldNames 返回结构体各字段在 JSON 中的名称
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// Validate 检查配置中的问题：各传输类型的必填字段、未知的键、重复的服务器名称，
+// 以及 stdio 服务器的命令是否存在于 PATH 中（已禁用的服务器不检查命令）。
+// 通过 LoadConfig 或 LoadLayeredConfig 加载的配置会同时检查原始文件，并在错误中给出文件与行列号。
+// 没有问题时返回 nil，否则返回 ValidationErrors
+func (c *Config) Validate() error {
+	// 每个服务器在其生效的配置层中定位
+	var validators []*configValidator
+	owners := make(map[string]*configValidator)
+	for _, layer := range c.layerConfigs() {
+		v := &configValidator{path: layer.config.path}
+		if layer.config.source != nil {
+			v.positions = make(map[string]int)
+			v.scan(layer.config.source)
+		}
+		for name := range layer.config.MCPServers {
+			owners[name] = v
+		}
+		validators = append(validators, v)
+	}
+	inCode := &configValidator{}
+	validators = append(validators, inCode)
+
+	names := make([]string, 0, len(c.MCPServers))
+	for name := range c.MCPServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v, exists := owners[name]
+		if !exists {
+			v = inCode
+		}
+		server := c.MCPServers[name]
+		v.validateServer("/mcpServers/"+escapePointer(name), name, &server)
+	}
+
+	var errs ValidationErrors
+	for _, v := range validators {
+		sort.SliceStable(v.errs, func(i, j int) bool {
+			a, b := v.errs[i], v.errs[j]
+			if a.Line != b.Line {
+				return a.Line < b.Line
+			}
+			if a.Column != b.Column {
+				return a.Column < b.Column
+			}
+			return a.Pointer < b.Pointer
+		})
+		errs = append(errs, v.errs...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// configValidator 收集配置中的问题
+type configValidator struct {
+	path      string
+	source    []byte
+	positions map[string]int // JSON Pointer 到其在 source 中的偏移
+	errs      ValidationErrors
+}
+
+// report 记录一个问题，pointer 在原始文件中不存在时使用最近的上级位置
+func (v *configValidator) report(pointer, format string, args ...any) {
+	err := &ValidationError{Path: v.path, Pointer: pointer, Message: fmt.Sprintf(format, args...)}
+	for p := pointer; v.positions != nil; {
+		if offset, ok := v.positions[p]; ok {
+			err.Line, err.Column = lineColumn(v.source, offset)
+			break
+		}
+		if p == "" {
+			break
+		}
+		p = p[:strings.LastIndexByte(p, '/')]
+	}
+	v.errs = append(v.errs, err)
+}
+
+// validateServer 检查单个服务器配置
+func (v *configValidator) validateServer(pointer, name string, server *ServerConfig) {
+	if strings.TrimSpace(name) == "" {
+		v.report(pointer, "server name must not be empty")
+	}
+
+	// 各传输类型使用的字段
+	transport := server.TransportType()
+	fields := map[string]bool{
+		"command":    server.Command != "",
+		"args":       len(server.Args) > 0,
+		"env":        len(server.Env) > 0,
+		"cwd":        server.Cwd != "",
+		"inheritEnv": server.InheritEnv != nil,
+		"stderr":     server.Stderr != "",
+		"url":        server.URL != "",
+		"socketPath": server.SocketPath != "",
+		"headers":    len(server.Headers) > 0,
+	}
+	var required string
+	var allowed []string
+	switch transport {
+	case TransportStdio:
+		required, allowed = "command", []string{"command", "args", "env", "cwd", "inheritEnv", "stderr"}
+	case TransportUnix:
+		required, allowed = "socketPath", []string{"socketPath"}
+	case TransportHTTP, TransportSSE, TransportWebSocket:
+		required, allowed = "url", []string{"url", "headers"}
+	default:
+		v.report(pointer+"/type", "unknown transport type %q, expected one of stdio, unix, http, sse, websocket", transport)
+		return
+	}
+
+	if !fields[required] {
+		v.report(pointer, "missing required field %q for %s server", required, transport)
+	}
+	for _, field := range []string{"command", "args", "env", "cwd", "inheritEnv", "stderr", "url", "socketPath", "headers"} {
+		if fields[field] && !slices.Contains(allowed, field) {
+			v.report(pointer+"/"+field, "field %q is not used by %s servers", field, transport)
+		}
+	}
+
+	if server.StartupTimeout < 0 {
+		v.report(pointer+"/startupTimeout", "timeout must not be negative")
+	}
+	if server.RequestTimeout < 0 {
+		v.report(pointer+"/requestTimeout", "timeout must not be negative")
+	}
+
+	switch transport {
+	case TransportStdio:
+		if server.Cwd != "" && !server.Disabled {
+			if info, err := os.Stat(server.Cwd); err != nil || !info.IsDir() {
+				v.report(pointer+"/cwd", "working directory %q does not exist", server.Cwd)
+			}
+		}
+		if server.Command != "" && !server.Disabled {
+			// 包含路径分隔符的相对路径相对于工作目录查找
+			command := server.Command
+			if server.Cwd != "" && !filepath.IsAbs(command) && strings.ContainsRune(command, filepath.Separator) {
+				command = filepath.Join(server.Cwd, command)
+			}
+			if _, err := exec.LookPath(command); err != nil {
+				v.report(pointer+"/command", "executable %q not found: %v", server.Command, err)
+			}
+		}
+	case TransportHTTP, TransportSSE, TransportWebSocket:
+		if server.URL != "" {
+			v.validateURL(pointer+"/url", transport, server.URL)
+		}
+	}
+}
+
+// validateURL 检查远程服务器的地址与传输类型是否匹配
+func (v *configValidator) validateURL(pointer, transport, rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		v.report(pointer, "invalid url: %v", err)
+		return
+	}
+	schemes := []string{"http", "https"}
+	if transport == TransportWebSocket {
+		schemes = []string{"ws", "wss"}
+	}
+	if !slices.Contains(schemes, u.Scheme) || u.Host == "" {
+		v.report(pointer, "invalid url %q for %s server, expected a %s:// or %s:// url", rawURL, transport, schemes[0], schemes[1])
+	}
+}
+
+// scan 遍历原始 JSON，记录各个键的位置，并检查未知与重复的键
+func (v *configValidator) scan(source []byte) {
+	v.source = source
+	dec := json.NewDecoder(bytes.NewReader(source))
+	if err := v.scanValue(dec, ""); err != nil {
+		v.report("", "invalid json: %v", err)
+	}
+}
+
+// scanValue 读取一个 JSON 值，pointer 为该值的位置
+func (v *configValidator) scanValue(dec *json.Decoder, pointer string) error {
+	v.positions[pointer] = v.nextOffset(dec)
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	if delim == '[' {
+		for i := 0; dec.More(); i++ {
+			if err := v.scanValue(dec, fmt.Sprintf("%s/%d", pointer, i)); err != nil {
+				return err
+			}
+		}
+		_, err := dec.Token()
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for dec.More() {
+		offset := v.nextOffset(dec)
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		child := pointer + "/" + escapePointer(key)
+
+		// 键重复时与 json.Unmarshal 一致，以最后一次出现为准
+		v.positions[child] = offset
+		if seen[key] {
+			if pointer == "/mcpServers" {
+				v.report(child, "duplicate server name %q", key)
+			} else {
+				v.report(child, "duplicate key %q", key)
+			}
+		}
+		seen[key] = true
+
+		if known := v.knownKeys(pointer); known != nil && !known[key] {
+			v.report(child, "unknown key %q%s", key, suggestKey(key, known))
+		}
+
+		if err := v.scanMember(dec, child); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
+
+// scanMember 读取对象成员的值，成员的位置保留为键名所在处
+func (v *configValidator) scanMember(dec *json.Decoder, pointer string) error {
+	offset := v.positions[pointer]
+	err := v.scanValue(dec, pointer)
+	v.positions[pointer] = offset
+	return err
+}
+
+// knownKeys 返回 pointer 处的对象允许出现的键，返回 nil 表示不检查
+func (v *configValidator) knownKeys(pointer string) map[string]bool {
+	switch {
+	case pointer == "":
+		return configKeys
+	case strings.HasPrefix(pointer, "/mcpServers/") && strings.Count(pointer, "/") == 2:
+		return serverConfigKeys
+	}
+	return nil
+}
+
+// nextOffset 返回下一个 token 在 source 中的起始位置
+func (v *configValidator) nextOffset(dec *json.Decoder) int {
+	offset := int(dec.InputOffset())
+	for offset < len(v.source) {
+		switch v.source[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// lineColumn 将字节偏移转换为行号与列号，列号按字符计算
+func lineColumn(source []byte, offset int) (line, column int) {
+	before := source[:min(offset, len(source))]
+	line = bytes.Count(before, []byte{'\n'}) + 1
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return line, utf8.RuneCount(before[lineStart:]) + 1
+}
+
+// escapePointer 按 RFC 6901 转义 JSON Pointer 中的一段
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// suggestKey 在 key 与某个已知的键只差一两个字符时给出提示
+func suggestKey(key string, known map[string]bool) string {
+	best, bestDistance := "", 3
+	for candidate := range known {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(candidate)); d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean %q?", best)
+}
+
+// editDistance 计算两个字符串的编辑距离
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
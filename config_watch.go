@@ -0,0 +1,319 @@
+package gomcp
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ConfigDiff 是两份配置中启用的服务器之间的差异，各列表按名称排序
+type ConfigDiff struct {
+	Added   []string // 新增或重新启用的服务器
+	Removed []string // 被移除或禁用的服务器
+	Changed []string // 配置发生变化的服务器
+}
+
+// Empty 判断两份配置中启用的服务器是否完全相同
+func (d ConfigDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffConfig 比较两份配置中启用的服务器，old 可以为 nil
+func DiffConfig(old, next *Config) ConfigDiff {
+	before, after := enabledServers(old), enabledServers(next)
+
+	var diff ConfigDiff
+	for name, server := range after {
+		previous, exists := before[name]
+		switch {
+		case !exists:
+			diff.Added = append(diff.Added, name)
+		case !reflect.DeepEqual(previous, server):
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range before {
+		if _, exists := after[name]; !exists {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
+// enabledServers 返回配置中启用的服务器
+func enabledServers(config *Config) map[string]ServerConfig {
+	servers := make(map[string]ServerConfig)
+	if config == nil {
+		return servers
+	}
+	for name, server := range config.MCPServers {
+		if !server.Disabled {
+			servers[name] = server
+		}
+	}
+	return servers
+}
+
+// ReloadEvent 是一次配置重新加载的结果。Err 不为空时新的配置被拒绝，原来的配置保持生效
+type ReloadEvent struct {
+	Path   string
+	Config *Config // 重新加载后生效的配置
+	Diff   ConfigDiff
+	Err    error
+	Time   time.Time
+}
+
+// WatcherOption 配置监视器的可选配置
+type WatcherOption func(*watcherOptions)
+
+type watcherOptions struct {
+	interval      time.Duration
+	configOptions []ConfigOption
+	handlers      []func(ReloadEvent)
+	logger        *slog.Logger
+}
+
+// WithWatchInterval 设置检查配置文件的间隔，默认为 1s
+func WithWatchInterval(interval time.Duration) WatcherOption {
+	return func(o *watcherOptions) {
+		o.interval = interval
+	}
+}
+
+// WithWatcherConfigOptions 设置加载配置时使用的选项，例如密钥解析器
+func WithWatcherConfigOptions(opts ...ConfigOption) WatcherOption {
+	return func(o *watcherOptions) {
+		o.configOptions = append(o.configOptions, opts...)
+	}
+}
+
+// WithReloadHandler 注册配置重新加载的回调，在监视器的 goroutine 中同步调用
+func WithReloadHandler(handler func(ReloadEvent)) WatcherOption {
+	return func(o *watcherOptions) {
+		o.handlers = append(o.handlers, handler)
+	}
+}
+
+// WithWatcherLogger 设置监视器使用的 logger，未设置时使用 slog.Default()
+func WithWatcherLogger(logger *slog.Logger) WatcherOption {
+	return func(o *watcherOptions) {
+		o.logger = logger
+	}
+}
+
+func (o *watcherOptions) log() *slog.Logger {
+	if o.logger == nil {
+		return slog.Default()
+	}
+	return o.logger
+}
+
+// ConfigWatcher 定期检查配置文件的修改时间与内容哈希，在内容变化时重新加载并校验配置。
+// 新的配置有效时交给监管器调整受影响的服务器；无效时拒绝新的配置，原来的配置保持生效
+type ConfigWatcher struct {
+	path       string
+	supervisor *Supervisor
+	opts       watcherOptions
+
+	mu      sync.Mutex // 保护下面的字段
+	config  *Config
+	stat    fileStamp
+	hash    [sha256.Size]byte
+	lastErr string        // 最近一次报告的错误，避免每次检查重复报告
+	stop    chan struct{} // 正在运行时不为 nil
+	done    chan struct{}
+}
+
+// fileStamp 是文件的修改时间与大小，用于快速判断文件是否可能发生变化
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewConfigWatcher 创建一个监视 path 的配置监视器，supervisor 可以为 nil，此时只通过回调报告变化
+func NewConfigWatcher(path string, supervisor *Supervisor, opts ...WatcherOption) *ConfigWatcher {
+	o := watcherOptions{interval: time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &ConfigWatcher{
+		path:       path,
+		supervisor: supervisor,
+		opts:       o,
+	}
+}
+
+// Start 加载当前的配置并开始监视。配置无效时返回错误；
+// 设置了监管器时会先将该配置交给监管器，监管器已经使用同样的配置时不会重启任何服务器
+func (w *ConfigWatcher) Start() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stop != nil {
+		return errors.New("config watcher already started")
+	}
+
+	config, stamp, hash, err := w.load()
+	if err != nil {
+		return err
+	}
+	w.config, w.stat, w.hash = config, stamp, hash
+	if w.supervisor != nil {
+		w.supervisor.Apply(config)
+	}
+
+	stop, done := make(chan struct{}), make(chan struct{})
+	w.stop, w.done = stop, done
+	go safe(w.opts.log(), func() { w.run(stop, done) })()
+	return nil
+}
+
+// Stop 停止监视并等待正在进行的重新加载完成，不会停止服务器。停止后可以再次调用 Start
+func (w *ConfigWatcher) Stop() {
+	w.mu.Lock()
+	stop, done := w.stop, w.done
+	w.stop, w.done = nil, nil
+	w.mu.Unlock()
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-done
+}
+
+// Config 返回当前生效的配置
+func (w *ConfigWatcher) Config() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.config
+}
+
+func (w *ConfigWatcher) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(w.opts.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check 检查配置文件是否变化，变化时重新加载
+func (w *ConfigWatcher) check() {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		w.reject(fmt.Errorf("failed to read config file: %w", err))
+		return
+	}
+	stamp := fileStamp{modTime: info.ModTime(), size: info.Size()}
+
+	w.mu.Lock()
+	unchanged := stamp == w.stat
+	w.mu.Unlock()
+	if unchanged {
+		return
+	}
+
+	config, stamp, hash, err := w.load()
+	if err != nil {
+		w.mu.Lock()
+		w.stat = stamp
+		w.mu.Unlock()
+		w.reject(err)
+		return
+	}
+
+	w.mu.Lock()
+	w.stat = stamp
+	w.lastErr = ""
+	if hash == w.hash {
+		// 内容与生效的配置相同，例如只更新了修改时间
+		w.mu.Unlock()
+		return
+	}
+	w.hash = hash
+	previous := w.config
+	w.config = config
+	w.mu.Unlock()
+
+	var diff ConfigDiff
+	if w.supervisor != nil {
+		diff = w.supervisor.Apply(config)
+	} else {
+		diff = DiffConfig(previous, config)
+	}
+	w.opts.log().Info("config reloaded",
+		slog.String("path", w.path),
+		slog.Any("added", diff.Added),
+		slog.Any("removed", diff.Removed),
+		slog.Any("changed", diff.Changed),
+	)
+	w.emit(ReloadEvent{Path: w.path, Config: config, Diff: diff})
+}
+
+// load 读取、解析并校验配置文件，同时返回文件的修改时间与内容哈希
+func (w *ConfigWatcher) load() (*Config, fileStamp, [sha256.Size]byte, error) {
+	var stamp fileStamp
+	var hash [sha256.Size]byte
+
+	file, err := os.Open(w.path)
+	if err != nil {
+		return nil, stamp, hash, fmt.Errorf("failed to read config file: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, stamp, hash, fmt.Errorf("failed to read config file: %w", err)
+	}
+	stamp = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, stamp, hash, fmt.Errorf("failed to read config file: %w", err)
+	}
+	hash = sha256.Sum256(data)
+
+	config, err := parseConfig(w.path, data, w.opts.configOptions...)
+	if err != nil {
+		return nil, stamp, hash, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, stamp, hash, fmt.Errorf("invalid config file: %w", err)
+	}
+	return config, stamp, hash, nil
+}
+
+// reject 报告无效的配置，同样的错误只报告一次
+func (w *ConfigWatcher) reject(err error) {
+	w.mu.Lock()
+	if err.Error() == w.lastErr {
+		w.mu.Unlock()
+		return
+	}
+	w.lastErr = err.Error()
+	config := w.config
+	w.mu.Unlock()
+
+	w.opts.log().Warn("config rejected, keeping the previous config", slog.String("path", w.path), slog.Any("error", err))
+	w.emit(ReloadEvent{Path: w.path, Config: config, Err: err})
+}
+
+func (w *ConfigWatcher) emit(event ReloadEvent) {
+	event.Time = time.Now()
+	for _, handler := range w.opts.handlers {
+		handler(event)
+	}
+}
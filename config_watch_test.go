@@ -0,0 +1,200 @@
+package gomcp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 测试配置差异 - 禁用视为移除，重新启用视为新增
+func TestDiffConfig(t *testing.T) {
+	old := &Config{MCPServers: map[string]ServerConfig{
+		"same":     {Command: "echo"},
+		"changed":  {Command: "echo", Args: []string{"1"}},
+		"removed":  {Command: "echo"},
+		"disabled": {Command: "echo"},
+		"enabled":  {Command: "echo", Disabled: true},
+	}}
+	next := &Config{MCPServers: map[string]ServerConfig{
+		"same":     {Command: "echo"},
+		"changed":  {Command: "echo", Args: []string{"2"}},
+		"disabled": {Command: "echo", Disabled: true},
+		"enabled":  {Command: "echo"},
+		"added":    {URL: "https://example.com/mcp"},
+	}}
+
+	diff := DiffConfig(old, next)
+	expected := ConfigDiff{
+		Added:   []string{"added", "enabled"},
+		Removed: []string{"disabled", "removed"},
+		Changed: []string{"changed"},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("配置差异错误: 期望 %+v, 得到 %+v", expected, diff)
+	}
+	if !DiffConfig(old, old).Empty() {
+		t.Error("相同的配置不应该有差异")
+	}
+}
+
+// 测试配置监视器 - 只调整受影响的服务器，无效的配置被拒绝
+func TestConfigWatcher_Reload(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	// 先写入临时文件再重命名，避免监视器读到写了一半的文件
+	write := func(data []byte) {
+		t.Helper()
+		tempPath := configPath + ".tmp"
+		if err := os.WriteFile(tempPath, data, 0644); err != nil {
+			t.Fatalf("写入配置文件失败: %v", err)
+		}
+		if err := os.Rename(tempPath, configPath); err != nil {
+			t.Fatalf("写入配置文件失败: %v", err)
+		}
+	}
+	writeConfig := func(servers map[string]ServerConfig) {
+		t.Helper()
+		data, err := json.Marshal(Config{MCPServers: servers})
+		if err != nil {
+			t.Fatalf("序列化配置失败: %v", err)
+		}
+		write(data)
+	}
+
+	changed := helperServerConfig("")
+	changed.Env["GOMCP_HELPER_REVISION"] = "2"
+	writeConfig(map[string]ServerConfig{
+		"keep":    helperServerConfig(""),
+		"change":  helperServerConfig(""),
+		"removed": helperServerConfig(""),
+	})
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	supervisor := NewSupervisor(config, WithRestartPolicy(RestartPolicy{Backoff: 10 * time.Millisecond}))
+	if err := supervisor.Start(); err != nil {
+		t.Fatalf("启动监管器失败: %v", err)
+	}
+	defer supervisor.Stop()
+	keepPid := waitForState(t, supervisor, "keep", ServerReady).Pid
+	changePid := waitForState(t, supervisor, "change", ServerReady).Pid
+	waitForState(t, supervisor, "removed", ServerReady)
+
+	events := make(chan ReloadEvent, 10)
+	watcher := NewConfigWatcher(configPath, supervisor,
+		WithWatchInterval(10*time.Millisecond),
+		WithReloadHandler(func(event ReloadEvent) { events <- event }),
+	)
+	if err := watcher.Start(); err != nil {
+		t.Fatalf("启动监视器失败: %v", err)
+	}
+	defer watcher.Stop()
+
+	// 启动时使用与监管器相同的配置，不应该重启任何服务器
+	if status, _ := supervisor.Status("change"); status.Pid != changePid {
+		t.Errorf("启动监视器不应该重启服务器: 期望 %d, 得到 %d", changePid, status.Pid)
+	}
+
+	waitForEvent := func() ReloadEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("等待重新加载超时")
+			return ReloadEvent{}
+		}
+	}
+
+	writeConfig(map[string]ServerConfig{
+		"keep":   helperServerConfig(""),
+		"change": changed,
+		"added":  helperServerConfig(""),
+	})
+	event := waitForEvent()
+	if event.Err != nil {
+		t.Fatalf("重新加载失败: %v", event.Err)
+	}
+	expected := ConfigDiff{Added: []string{"added"}, Removed: []string{"removed"}, Changed: []string{"change"}}
+	if !reflect.DeepEqual(event.Diff, expected) {
+		t.Errorf("配置差异错误: 期望 %+v, 得到 %+v", expected, event.Diff)
+	}
+
+	if status := waitForState(t, supervisor, "change", ServerReady); status.Pid == changePid {
+		t.Error("配置变化的服务器应该重启")
+	}
+	waitForState(t, supervisor, "added", ServerReady)
+	if _, exists := supervisor.Status("removed"); exists {
+		t.Error("被移除的服务器应该停止")
+	}
+	if status, _ := supervisor.Status("keep"); status.Pid != keepPid || status.State != ServerReady {
+		t.Errorf("未变化的服务器不应该重启: %+v", status)
+	}
+
+	// 无效的配置被拒绝，原来的配置保持生效
+	write([]byte(`{"mcpServers": {"keep": {"comand": "echo"}}}`))
+	event = waitForEvent()
+	if event.Err == nil {
+		t.Fatal("无效的配置应该被拒绝")
+	}
+	if _, exists := event.Config.MCPServers["added"]; !exists || watcher.Config() != event.Config {
+		t.Error("拒绝无效配置后应该保持原来的配置")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if status, _ := supervisor.Status("keep"); status.Pid != keepPid || status.State != ServerReady {
+		t.Errorf("拒绝无效配置后服务器不应该变化: %+v", status)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("同样的错误只应该报告一次: %+v", event)
+	default:
+	}
+}
+
+// 测试监视器停止后可以再次启动
+func TestConfigWatcher_Restart(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"mcpServers": {}}`), 0644); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+
+	events := make(chan ReloadEvent, 10)
+	watcher := NewConfigWatcher(configPath, nil,
+		WithWatchInterval(10*time.Millisecond),
+		WithReloadHandler(func(event ReloadEvent) { events <- event }),
+	)
+	if err := watcher.Start(); err != nil {
+		t.Fatalf("启动监视器失败: %v", err)
+	}
+	if err := watcher.Start(); err == nil {
+		t.Error("重复启动监视器应该返回错误")
+	}
+	watcher.Stop()
+	watcher.Stop()
+
+	if err := watcher.Start(); err != nil {
+		t.Fatalf("停止后再次启动监视器失败: %v", err)
+	}
+	defer watcher.Stop()
+
+	// 先写入临时文件再重命名，避免监视器读到写了一半的文件
+	tempPath := configPath + ".tmp"
+	if err := os.WriteFile(tempPath, []byte(`{"mcpServers": {"added": {"command": "echo"}}}`), 0644); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	if err := os.Rename(tempPath, configPath); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	select {
+	case event := <-events:
+		if event.Err != nil || !reflect.DeepEqual(event.Diff.Added, []string{"added"}) {
+			t.Errorf("重新加载结果错误: %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("再次启动后应该继续监视配置文件")
+	}
+}
@@ -0,0 +1,14 @@
+package gomcp
+
+// Content 是 MCP 消息中的一段内容，Type 为 text、image 或 audio
+type Content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"` // base64 编码的图片或音频数据
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// TextContent 创建一段文本内容
+func TextContent(text string) Content {
+	return Content{Type: "text", Text: text}
+}
@@ -0,0 +1,223 @@
+package gomcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"slices"
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+// ElicitAction 是用户对信息请求的处理结果
+type ElicitAction string
+
+const (
+	// ElicitActionAccept 用户提交了内容
+	ElicitActionAccept ElicitAction = "accept"
+	// ElicitActionDecline 用户明确拒绝提供内容
+	ElicitActionDecline ElicitAction = "decline"
+	// ElicitActionCancel 用户关闭了对话框，没有做出选择
+	ElicitActionCancel ElicitAction = "cancel"
+)
+
+// PropertySchema 描述信息请求中的一个字段，只支持 string、number、integer、boolean 等基础类型
+type PropertySchema struct {
+	Type        string   `json:"type"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	EnumNames   []string `json:"enumNames,omitempty"`
+	Format      string   `json:"format,omitempty"` // email、uri、date 或 date-time
+	MinLength   *int     `json:"minLength,omitempty"`
+	MaxLength   *int     `json:"maxLength,omitempty"`
+	Minimum     *float64 `json:"minimum,omitempty"`
+	Maximum     *float64 `json:"maximum,omitempty"`
+	Default     any      `json:"default,omitempty"`
+}
+
+// ElicitationSchema 是信息请求的扁平 JSON Schema，Type 固定为 object，属性不能嵌套
+type ElicitationSchema struct {
+	Type       string                    `json:"type"`
+	Properties map[string]PropertySchema `json:"properties"`
+	Required   []string                  `json:"required,omitempty"`
+}
+
+// ElicitRequest 是 elicitation/create 请求的参数
+type ElicitRequest struct {
+	Message         string            `json:"message"`
+	RequestedSchema ElicitationSchema `json:"requestedSchema"`
+}
+
+// ElicitResult 是 elicitation/create 请求的结果，只有 Action 为 accept 时 Content 才有值
+type ElicitResult struct {
+	Action  ElicitAction           `json:"action"`
+	Content map[string]interface{} `json:"content,omitempty"`
+}
+
+// ElicitationHandler 处理服务器发来的信息请求，通常由宿主向用户展示表单并返回用户的选择
+type ElicitationHandler func(ctx context.Context, request *ElicitRequest) (*ElicitResult, error)
+
+// Elicit 请求客户端向用户收集结构化的输入，阻塞直到用户做出选择。
+// 用户接受时返回的内容已经按照 RequestedSchema 校验过。客户端需要通过 WithElicitationHandler 支持信息请求
+func (s *Session) Elicit(ctx context.Context, request ElicitRequest) (*ElicitResult, error) {
+	if request.RequestedSchema.Type == "" {
+		request.RequestedSchema.Type = "object"
+	}
+	if err := request.RequestedSchema.check(); err != nil {
+		return nil, err
+	}
+
+	var params map[string]interface{}
+	if err := remarshal(request, &params); err != nil {
+		return nil, fmt.Errorf("failed to marshal elicitation request: %w", err)
+	}
+
+	raw, err := s.Call(ctx, "elicitation/create", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var result ElicitResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal elicitation result: %w", err)
+	}
+
+	switch result.Action {
+	case ElicitActionAccept:
+		if err := request.RequestedSchema.Validate(result.Content); err != nil {
+			return nil, fmt.Errorf("invalid elicitation content: %w", err)
+		}
+	case ElicitActionDecline, ElicitActionCancel:
+		result.Content = nil
+	default:
+		return nil, fmt.Errorf("invalid elicitation action: %q", result.Action)
+	}
+	return &result, nil
+}
+
+// WithElicitationHandler 设置处理服务器信息请求（elicitation/create）的处理器
+func WithElicitationHandler(handler ElicitationHandler) ClientOption {
+	return WithRequestHandler("elicitation/create", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		var request ElicitRequest
+		if err := remarshal(params, &request); err != nil {
+			return nil, NewError(InvalidParams, fmt.Sprintf("invalid elicitation request: %v", err), nil)
+		}
+		return handler(ctx, &request)
+	})
+}
+
+// check 检查 schema 本身是否合法：类型为 object，属性都是基础类型，必填字段都已定义
+func (s ElicitationSchema) check() error {
+	if s.Type != "object" {
+		return fmt.Errorf("elicitation schema type must be object, got %q", s.Type)
+	}
+	for name, property := range s.Properties {
+		switch property.Type {
+		case "string", "number", "integer", "boolean":
+		default:
+			return fmt.Errorf("elicitation property %q has unsupported type %q", name, property.Type)
+		}
+	}
+	for _, name := range s.Required {
+		if _, exists := s.Properties[name]; !exists {
+			return fmt.Errorf("required elicitation property %q is not defined", name)
+		}
+	}
+	return nil
+}
+
+// Validate 按照 schema 校验用户提交的内容，返回的错误中包含所有不合法的字段
+func (s ElicitationSchema) Validate(content map[string]interface{}) error {
+	var errs []error
+	for _, name := range s.Required {
+		if _, exists := content[name]; !exists {
+			errs = append(errs, fmt.Errorf("%s: required", name))
+		}
+	}
+
+	names := make([]string, 0, len(content))
+	for name := range content {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, exists := s.Properties[name]
+		if !exists {
+			errs = append(errs, fmt.Errorf("%s: unknown property", name))
+			continue
+		}
+		if err := property.validate(content[name]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p PropertySchema) validate(value any) error {
+	switch p.Type {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected string, got %T", value)
+		}
+		return p.validateString(s)
+	case "number", "integer":
+		n, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("expected %s, got %T", p.Type, value)
+		}
+		if p.Type == "integer" && n != math.Trunc(n) {
+			return fmt.Errorf("expected integer, got %v", n)
+		}
+		if p.Minimum != nil && n < *p.Minimum {
+			return fmt.Errorf("must be >= %v", *p.Minimum)
+		}
+		if p.Maximum != nil && n > *p.Maximum {
+			return fmt.Errorf("must be <= %v", *p.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("expected boolean, got %T", value)
+		}
+	}
+	return nil
+}
+
+func (p PropertySchema) validateString(s string) error {
+	length := utf8.RuneCountInString(s)
+	if p.MinLength != nil && length < *p.MinLength {
+		return fmt.Errorf("must be at least %d characters", *p.MinLength)
+	}
+	if p.MaxLength != nil && length > *p.MaxLength {
+		return fmt.Errorf("must be at most %d characters", *p.MaxLength)
+	}
+	if len(p.Enum) > 0 && !slices.Contains(p.Enum, s) {
+		return fmt.Errorf("must be one of %v", p.Enum)
+	}
+
+	var err error
+	switch p.Format {
+	case "email":
+		_, err = mail.ParseAddress(s)
+	case "uri":
+		var u *url.URL
+		if u, err = url.ParseRequestURI(s); err == nil && u.Scheme == "" {
+			err = errors.New("missing scheme")
+		}
+	case "date":
+		_, err = time.Parse(time.DateOnly, s)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, s)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", p.Format, err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package gomcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 测试信息请求 - 处理器向用户确认部署参数
+func TestElicitation_Elicit(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "elicitation_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "test.sock")
+	server := NewUnixServer(socketPath)
+	server.RegisterHandler("deploy", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		result, err := SessionFromContext(ctx).Elicit(ctx, ElicitRequest{
+			Message: "Confirm deployment",
+			RequestedSchema: ElicitationSchema{
+				Properties: map[string]PropertySchema{
+					"environment": {Type: "string", Enum: []string{"staging", "production"}},
+					"replicas":    {Type: "integer"},
+				},
+				Required: []string{"environment"},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name     string
+		result   *ElicitResult
+		expected string
+		errMsg   string
+	}{
+		{
+			name:     "接受",
+			result:   &ElicitResult{Action: ElicitActionAccept, Content: map[string]interface{}{"environment": "staging", "replicas": 3}},
+			expected: `{"action":"accept","content":{"environment":"staging","replicas":3}}`,
+		},
+		{
+			name:     "拒绝",
+			result:   &ElicitResult{Action: ElicitActionDecline, Content: map[string]interface{}{"environment": "staging"}},
+			expected: `{"action":"decline"}`,
+		},
+		{
+			name:   "内容不合法",
+			result: &ElicitResult{Action: ElicitActionAccept, Content: map[string]interface{}{"environment": "dev"}},
+			errMsg: "environment: must be one of [staging production]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received *ElicitRequest
+			client, err := NewUnixClient(socketPath, WithElicitationHandler(func(ctx context.Context, request *ElicitRequest) (*ElicitResult, error) {
+				received = request
+				return tt.result, nil
+			}))
+			if err != nil {
+				t.Fatalf("创建客户端失败: %v", err)
+			}
+			defer client.Close()
+
+			result, err := client.Call(ctx, "deploy", nil)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("应该返回包含 %q 的错误, 得到 %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("调用失败: %v", err)
+			}
+			if string(result) != tt.expected {
+				t.Errorf("响应中的result字段错误: 期望 %s, 得到 %s", tt.expected, result)
+			}
+			if received == nil || received.Message != "Confirm deployment" || received.RequestedSchema.Type != "object" {
+				t.Errorf("客户端收到的信息请求错误: %+v", received)
+			}
+		})
+	}
+}
+
+// 测试信息请求 - schema 校验
+func TestElicitationSchema_Validate(t *testing.T) {
+	minLength, minimum := 3, 1.0
+	schema := ElicitationSchema{
+		Type: "object",
+		Properties: map[string]PropertySchema{
+			"name":    {Type: "string", MinLength: &minLength},
+			"email":   {Type: "string", Format: "email"},
+			"count":   {Type: "integer", Minimum: &minimum},
+			"confirm": {Type: "boolean"},
+		},
+		Required: []string{"name", "confirm"},
+	}
+
+	if err := schema.Validate(map[string]interface{}{
+		"name":    "alice",
+		"email":   "alice@example.com",
+		"count":   float64(2),
+		"confirm": true,
+	}); err != nil {
+		t.Errorf("合法的内容不应该返回错误: %v", err)
+	}
+
+	err := schema.Validate(map[string]interface{}{
+		"name":  "al",
+		"email": "not-an-email",
+		"count": 1.5,
+		"extra": "x",
+	})
+	if err == nil {
+		t.Fatal("不合法的内容应该返回错误")
+	}
+	for _, expected := range []string{"confirm: required", "name: must be at least 3 characters", "email: invalid email", "count: expected integer", "extra: unknown property"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("错误中应该包含 %q: %v", expected, err)
+		}
+	}
+
+	nested := ElicitationSchema{Type: "object", Properties: map[string]PropertySchema{"config": {Type: "object"}}}
+	if err := nested.check(); err == nil {
+		t.Error("嵌套的 schema 应该返回错误")
+	}
+}
@@ -1,5 +1,10 @@
 package gomcp
 
+import (
+	"errors"
+	"fmt"
+)
+
 type ErrorCode int
 
 // 参考了Python 的 sdk https://github.com/modelcontextprotocol/python-sdk/blob/08f4e01b8f9ab77417f08738bb5cec26a5ebc94f/src/mcp/types.py#L144
@@ -9,10 +14,67 @@ var (
 	MethodNotFound ErrorCode = -32601
 	InvalidParams  ErrorCode = -32602
 	InternalError  ErrorCode = -32603
+
+	// ResourceNotFound 请求的资源不存在，MCP 规范定义的错误码
+	ResourceNotFound ErrorCode = -32002
 )
 
-// Error 错误信息
+// Error 错误信息，同时实现了 error 接口
+//
+// 处理器返回 *Error 时，服务器会原样保留其 Code 与 Data 写入响应；
+// 客户端可以通过 errors.As 取出服务器返回的 *Error 并根据 Code 做分支处理。
 type Error struct {
 	Code    ErrorCode `json:"code"`
 	Message string    `json:"message"`
+	Data    any       `json:"data,omitempty"`
+}
+
+// NewError 创建一个带有错误码和附加数据的错误
+func NewError(code ErrorCode, message string, data any) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+		Data:    data,
+	}
+}
+
+// Error 实现 error 接口
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+// toError 将处理器返回的错误转换为 JSON-RPC 错误，非 *Error 类型的错误统一视为 InternalError
+func toError(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	return &Error{
+		Code:    InternalError,
+		Message: err.Error(),
+	}
 }
+
+// sendError 表示请求在发送给对端之前就失败了，对端不会处理该请求，可以安全地重试
+type sendError struct {
+	err error
+}
+
+func (e *sendError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sendError) Unwrap() error {
+	return e.err
+}
+
+var (
+	// ErrClosed 连接已关闭
+	ErrClosed = errors.New("connection closed")
+	// ErrTimeout 等待响应超时
+	ErrTimeout = errors.New("timeout waiting for response")
+	// ErrServerClosed 服务器已经关闭
+	ErrServerClosed = errors.New("server closed")
+	// ErrHeartbeatTimeout 对端连续多次未响应心跳
+	ErrHeartbeatTimeout = errors.New("heartbeat timeout")
+)
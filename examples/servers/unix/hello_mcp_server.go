@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -23,12 +24,13 @@ func main() {
 
 func helloServer() {
 	server := gomcp.NewUnixServer("/tmp/mcp.sock")
-	server.RegisterHandler("hello", func(params map[string]interface{}) (interface{}, error) {
+	server.RegisterHandler("hello", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 		fmt.Printf("Received request: %+v\n", params)
 		return "Hello World!", nil
 	})
-	server.RegisterHandler("close", func(params map[string]interface{}) (interface{}, error) {
-		server.Stop()
+	server.RegisterHandler("close", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		// 在处理器返回之后才能完成关闭，因此在新的 goroutine 中等待
+		go server.Shutdown(context.Background())
 		return "close", nil
 	})
 	server.Start()
@@ -0,0 +1,91 @@
+package gomcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// defaultHeartbeatMisses 是未指定时允许连续未响应的心跳次数
+const defaultHeartbeatMisses = 3
+
+// heartbeatOptions 心跳配置，interval 为 0 时不发送心跳
+type heartbeatOptions struct {
+	interval  time.Duration
+	maxMisses int
+}
+
+func newHeartbeatOptions(interval time.Duration, maxMisses int) heartbeatOptions {
+	if maxMisses <= 0 {
+		maxMisses = defaultHeartbeatMisses
+	}
+	return heartbeatOptions{interval: interval, maxMisses: maxMisses}
+}
+
+// WithClientHeartbeat 每隔 interval 向服务器发送一次 ping，连续 maxMisses 次未在 interval 内收到响应时
+// 关闭连接，之后的调用返回包装了 ErrHeartbeatTimeout 的 ErrClosed。maxMisses 不大于 0 时使用 3
+func WithClientHeartbeat(interval time.Duration, maxMisses int) ClientOption {
+	return func(o *clientOptions) {
+		o.heartbeat = newHeartbeatOptions(interval, maxMisses)
+	}
+}
+
+// WithServerHeartbeat 每隔 interval 向每个会话的客户端发送一次 ping，
+// 连续 maxMisses 次未在 interval 内收到响应时关闭该会话。maxMisses 不大于 0 时使用 3
+func WithServerHeartbeat(interval time.Duration, maxMisses int) ServerOption {
+	return func(o *serverOptions) {
+		o.heartbeat = newHeartbeatOptions(interval, maxMisses)
+	}
+}
+
+// run 按间隔调用 ping，连续失败 maxMisses 次后返回最后一次的错误，done 关闭时返回 nil
+func (h heartbeatOptions) run(done <-chan struct{}, ping func(ctx context.Context) error, logger *slog.Logger) error {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	misses := 0
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), h.interval)
+		err := ping(ctx)
+		cancel()
+		if err == nil {
+			misses = 0
+			continue
+		}
+
+		select {
+		case <-done:
+			return nil
+		default:
+		}
+		misses++
+		logger.Debug("heartbeat missed", slog.Int("misses", misses), slog.Any("error", err))
+		if misses >= h.maxMisses {
+			return fmt.Errorf("%w: %d consecutive pings failed: %v", ErrHeartbeatTimeout, misses, err)
+		}
+	}
+}
+
+// ping 处理 ping 请求，返回空结果
+func ping(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+// Ping 向服务器发送 ping 请求，检查连接是否可用
+func Ping(ctx context.Context, client Client) error {
+	_, err := client.Call(ctx, "ping", nil)
+	return err
+}
+
+// Ping 向客户端发送 ping 请求，检查会话是否可用
+func (s *Session) Ping(ctx context.Context) error {
+	_, err := s.Call(ctx, "ping", nil)
+	return err
+}
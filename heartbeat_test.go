@@ -0,0 +1,133 @@
+package gomcp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 测试 ping - 客户端与服务器都会自动响应 ping，正常响应的连接不会被心跳关闭
+func TestPing(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "ping_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "test.sock")
+	server := NewUnixServer(socketPath, WithServerHeartbeat(20*time.Millisecond, 3))
+	server.RegisterHandler("ping_client", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return "pong", SessionFromContext(ctx).Ping(ctx)
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	client, err := NewUnixClient(socketPath, WithClientHeartbeat(20*time.Millisecond, 3))
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	time.Sleep(100 * time.Millisecond)
+	if err := Ping(ctx, client); err != nil {
+		t.Errorf("ping 服务器失败: %v", err)
+	}
+	if _, err := client.Call(ctx, "ping_client", nil); err != nil {
+		t.Errorf("服务器 ping 客户端失败: %v", err)
+	}
+}
+
+// 测试客户端心跳 - 服务器无响应时关闭连接
+func TestClientHeartbeat_Timeout(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "heartbeat_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// 只接受连接、从不响应的服务器
+	socketPath := filepath.Join(tempDir, "test.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		bufio.NewReader(conn).WriteTo(io.Discard)
+	}()
+
+	client, err := NewUnixClient(socketPath, WithClientHeartbeat(20*time.Millisecond, 2))
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 等待中的请求在心跳超时后返回
+	_, err = client.Call(ctx, "slow", nil)
+	if !errors.Is(err, ErrClosed) || !errors.Is(err, ErrHeartbeatTimeout) {
+		t.Errorf("期望心跳超时错误, 得到 %v", err)
+	}
+
+	// ReceiveResponse 不再无限阻塞
+	if _, err := client.ReceiveResponse(); !errors.Is(err, ErrHeartbeatTimeout) {
+		t.Errorf("期望心跳超时错误, 得到 %v", err)
+	}
+}
+
+// 测试服务器心跳 - 客户端无响应时关闭会话
+func TestServerHeartbeat_Timeout(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "heartbeat_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "test.sock")
+	server := NewUnixServer(socketPath, WithServerHeartbeat(20*time.Millisecond, 2))
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	// 从不响应 ping 的客户端
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var pings int
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), `"method":"ping"`) {
+			pings++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("服务器应该关闭连接: %v", err)
+	}
+	if pings != 2 {
+		t.Errorf("ping 次数错误: 期望 2, 得到 %d", pings)
+	}
+}
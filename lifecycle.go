@@ -0,0 +1,141 @@
+package gomcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// LatestProtocolVersion 是当前支持的最新 MCP 协议版本
+const LatestProtocolVersion = "2025-06-18"
+
+// supportedProtocolVersions 是服务器支持的 MCP 协议版本
+var supportedProtocolVersions = []string{
+	LatestProtocolVersion,
+	"2025-03-26",
+	"2024-11-05",
+}
+
+// Implementation 描述 MCP 客户端或服务器的名称与版本
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// WithClientInfo 设置客户端在 initialize 请求中提供的名称与版本
+func WithClientInfo(name, version string) ClientOption {
+	return func(o *clientOptions) {
+		o.clientInfo = Implementation{Name: name, Version: version}
+	}
+}
+
+// WithServerInfo 设置服务器在 initialize 响应中返回的名称与版本
+func WithServerInfo(name, version string) ServerOption {
+	return func(o *serverOptions) {
+		o.serverInfo = Implementation{Name: name, Version: version}
+	}
+}
+
+// initialize 处理 initialize 请求，返回协商的协议版本与服务器能力。
+// 客户端请求的版本不受支持时返回最新的版本，由客户端决定是否继续
+func (c *serverCore) initialize(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	version, _ := params["protocolVersion"].(string)
+	if !slices.Contains(supportedProtocolVersions, version) {
+		version = LatestProtocolVersion
+	}
+
+	serverInfo := c.opts.serverInfo
+	if serverInfo.Name == "" {
+		serverInfo.Name = "gomcp"
+	}
+
+	capabilities := c.capabilities()
+	if session := SessionFromContext(ctx); session != nil {
+		var client struct {
+			ClientInfo   Implementation         `json:"clientInfo"`
+			Capabilities map[string]interface{} `json:"capabilities"`
+		}
+		if err := remarshal(params, &client); err != nil {
+			return nil, NewError(InvalidParams, fmt.Sprintf("invalid initialize request: %v", err), nil)
+		}
+		session.initialized(version, client.ClientInfo, client.Capabilities, capabilities)
+	}
+
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities":    capabilities,
+		"serverInfo":      serverInfo,
+	}, nil
+}
+
+// capabilities 返回服务器声明的能力。工具、提示词与资源可以在运行时增删，
+// 因此总是声明这三项能力并支持 listChanged；completion/complete 总是可用，补全能力同样总是声明
+func (c *serverCore) capabilities() map[string]interface{} {
+	return map[string]interface{}{
+		"logging":     map[string]interface{}{},
+		"tools":       map[string]interface{}{"listChanged": true},
+		"prompts":     map[string]interface{}{"listChanged": true},
+		"resources":   map[string]interface{}{"listChanged": true},
+		"completions": map[string]interface{}{},
+	}
+}
+
+// InitializeResult 是 initialize 请求的结果
+type InitializeResult struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ServerInfo      Implementation         `json:"serverInfo"`
+	Instructions    string                 `json:"instructions,omitempty"`
+}
+
+// Initialize 与服务器完成 MCP 握手：发送 initialize 请求，检查服务器选择的协议版本，
+// 然后发送 notifications/initialized 通知。服务器选择了不支持的版本时返回错误
+func Initialize(ctx context.Context, client Client, clientInfo Implementation, capabilities map[string]interface{}) (*InitializeResult, error) {
+	if clientInfo.Name == "" {
+		clientInfo.Name = "gomcp"
+	}
+	if capabilities == nil {
+		capabilities = map[string]interface{}{}
+	}
+
+	raw, err := client.Call(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": LatestProtocolVersion,
+		"capabilities":    capabilities,
+		"clientInfo":      clientInfo,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result InitializeResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal initialize result: %w", err)
+	}
+	if !slices.Contains(supportedProtocolVersions, result.ProtocolVersion) {
+		return nil, fmt.Errorf("unsupported protocol version: %s", result.ProtocolVersion)
+	}
+	if setter, ok := client.(protocolVersionSetter); ok {
+		setter.setProtocolVersion(result.ProtocolVersion)
+	}
+
+	if err := client.Notify("notifications/initialized", nil); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// capabilities 根据客户端的配置返回 initialize 时声明的能力
+func (o *clientOptions) capabilities() map[string]interface{} {
+	capabilities := map[string]interface{}{}
+	if o.roots != nil {
+		capabilities["roots"] = map[string]interface{}{"listChanged": true}
+	}
+	if _, ok := o.requestHandlers["sampling/createMessage"]; ok {
+		capabilities["sampling"] = map[string]interface{}{}
+	}
+	if _, ok := o.requestHandlers["elicitation/create"]; ok {
+		capabilities["elicitation"] = map[string]interface{}{}
+	}
+	return capabilities
+}
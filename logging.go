@@ -0,0 +1,232 @@
+package gomcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// LoggingLevel 是 MCP 日志级别，取值参考 RFC 5424 中的 syslog 严重性
+type LoggingLevel string
+
+const (
+	LoggingLevelDebug     LoggingLevel = "debug"
+	LoggingLevelInfo      LoggingLevel = "info"
+	LoggingLevelNotice    LoggingLevel = "notice"
+	LoggingLevelWarning   LoggingLevel = "warning"
+	LoggingLevelError     LoggingLevel = "error"
+	LoggingLevelCritical  LoggingLevel = "critical"
+	LoggingLevelAlert     LoggingLevel = "alert"
+	LoggingLevelEmergency LoggingLevel = "emergency"
+)
+
+// 在 slog 内置级别之外补充 RFC 5424 中的级别，供会话 logger 使用
+const (
+	LevelNotice    = slog.Level(2)
+	LevelCritical  = slog.Level(12)
+	LevelAlert     = slog.Level(16)
+	LevelEmergency = slog.Level(20)
+)
+
+// loggingLevels 按严重性从低到高排列
+var loggingLevels = []struct {
+	name  LoggingLevel
+	level slog.Level
+}{
+	{LoggingLevelDebug, slog.LevelDebug},
+	{LoggingLevelInfo, slog.LevelInfo},
+	{LoggingLevelNotice, LevelNotice},
+	{LoggingLevelWarning, slog.LevelWarn},
+	{LoggingLevelError, slog.LevelError},
+	{LoggingLevelCritical, LevelCritical},
+	{LoggingLevelAlert, LevelAlert},
+	{LoggingLevelEmergency, LevelEmergency},
+}
+
+// slogLevel 返回日志级别对应的 slog 级别
+func (l LoggingLevel) slogLevel() (slog.Level, bool) {
+	for _, item := range loggingLevels {
+		if item.name == l {
+			return item.level, true
+		}
+	}
+	return 0, false
+}
+
+// loggingLevelOf 返回不高于 slog 级别的最严重的日志级别
+func loggingLevelOf(level slog.Level) LoggingLevel {
+	name := LoggingLevelDebug
+	for _, item := range loggingLevels {
+		if level >= item.level {
+			name = item.name
+		}
+	}
+	return name
+}
+
+// LoggingMessage 是服务器通过 notifications/message 发送给客户端的日志
+type LoggingMessage struct {
+	Level  LoggingLevel `json:"level"`
+	Logger string       `json:"logger,omitempty"`
+	Data   any          `json:"data"`
+}
+
+// LoggingLevel 返回客户端通过 logging/setLevel 设置的日志级别，未设置时返回空字符串
+func (s *Session) LoggingLevel() LoggingLevel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.logLevel
+}
+
+// Logger 返回会话范围的 logger，name 作为日志通知中的 logger 名称。
+// 级别不低于客户端设置的日志级别的记录会以 notifications/message 通知发送给客户端，
+// 客户端未设置日志级别之前不会发送任何记录。s 为 nil 时返回的 logger 会丢弃所有记录
+func (s *Session) Logger(name string) *slog.Logger {
+	return slog.New(&sessionLogHandler{session: s, name: name})
+}
+
+// setLoggingLevel 处理 logging/setLevel 请求
+func (c *serverCore) setLoggingLevel(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	session := SessionFromContext(ctx)
+	if session == nil {
+		return nil, NewError(InternalError, "logging/setLevel requires a session", nil)
+	}
+
+	level, _ := params["level"].(string)
+	if _, ok := LoggingLevel(level).slogLevel(); !ok {
+		return nil, NewError(InvalidParams, fmt.Sprintf("invalid logging level: %s", level), nil)
+	}
+
+	session.mu.Lock()
+	session.logLevel = LoggingLevel(level)
+	session.mu.Unlock()
+	return map[string]interface{}{}, nil
+}
+
+// sessionLogHandler 将 slog 记录转换为 notifications/message 通知
+type sessionLogHandler struct {
+	session *Session
+	name    string
+	attrs   []groupedAttr
+	groups  []string
+}
+
+// groupedAttr 记录通过 WithAttrs 添加的属性以及添加时所在的分组
+type groupedAttr struct {
+	groups []string
+	attr   slog.Attr
+}
+
+func (h *sessionLogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if h.session == nil {
+		return false
+	}
+	minLevel, ok := h.session.LoggingLevel().slogLevel()
+	return ok && level >= minLevel
+}
+
+func (h *sessionLogHandler) Handle(_ context.Context, r slog.Record) error {
+	data := make(map[string]interface{})
+	if r.Message != "" {
+		data["message"] = r.Message
+	}
+	for _, item := range h.attrs {
+		putAttr(data, item.groups, item.attr)
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		putAttr(data, h.groups, attr)
+		return true
+	})
+
+	params := map[string]interface{}{
+		"level": loggingLevelOf(r.Level),
+		"data":  data,
+	}
+	if h.name != "" {
+		params["logger"] = h.name
+	}
+	return h.session.Notify("notifications/message", params)
+}
+
+func (h *sessionLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]groupedAttr(nil), h.attrs...)
+	for _, attr := range attrs {
+		clone.attrs = append(clone.attrs, groupedAttr{groups: h.groups, attr: attr})
+	}
+	return &clone
+}
+
+func (h *sessionLogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.groups = append(append([]string(nil), h.groups...), name)
+	return &clone
+}
+
+// putAttr 将属性写入 data 中 groups 指定的嵌套位置
+func putAttr(data map[string]interface{}, groups []string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	for _, group := range groups {
+		child, ok := data[group].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			data[group] = child
+		}
+		data = child
+	}
+
+	if attr.Value.Kind() != slog.KindGroup {
+		data[attr.Key] = attrValue(attr.Value)
+		return
+	}
+
+	// 分组属性的 key 为空时，其中的属性直接写入当前层级
+	if attr.Key != "" {
+		child, ok := data[attr.Key].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			data[attr.Key] = child
+		}
+		data = child
+	}
+	for _, item := range attr.Value.Group() {
+		putAttr(data, nil, item)
+	}
+}
+
+// attrValue 将属性值转换为可以被 JSON 编码的值
+func attrValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+	}
+	return v.Any()
+}
+
+// WithLoggingHandler 设置接收服务器日志通知（notifications/message）的回调
+func WithLoggingHandler(handler func(msg LoggingMessage)) ClientOption {
+	return WithNotificationHandler("notifications/message", func(method string, params map[string]interface{}) {
+		var msg LoggingMessage
+		if err := remarshal(params, &msg); err != nil {
+			return
+		}
+		handler(msg)
+	})
+}
+
+// SetLoggingLevel 请求服务器只发送不低于 level 的日志通知
+func SetLoggingLevel(ctx context.Context, client Client, level LoggingLevel) error {
+	_, err := client.Call(ctx, "logging/setLevel", map[string]interface{}{"level": level})
+	return err
+}
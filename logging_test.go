@@ -0,0 +1,137 @@
+package gomcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 测试 MCP 日志 - 客户端设置级别后接收会话 logger 发出的日志通知
+func TestLogging_SessionLogger(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "logging_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "test.sock")
+	server := NewUnixServer(socketPath)
+	server.RegisterHandler("work", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		logger := SessionFromContext(ctx).Logger("worker").With(slog.String("job", "build"))
+		logger.Debug("debug detail")
+		logger.Info("started", slog.Int("step", 1))
+		logger.WithGroup("result").Log(ctx, LevelCritical, "failed", slog.Any("error", errors.New("disk full")))
+		return "done", nil
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	messages := make(chan LoggingMessage, 10)
+	client, err := NewUnixClient(socketPath, WithLoggingHandler(func(msg LoggingMessage) {
+		messages <- msg
+	}))
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 未设置级别之前不发送日志
+	if _, err := client.Call(ctx, "work", nil); err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+
+	if err := SetLoggingLevel(ctx, client, LoggingLevelInfo); err != nil {
+		t.Fatalf("设置日志级别失败: %v", err)
+	}
+	if _, err := client.Call(ctx, "work", nil); err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+
+	var received []LoggingMessage
+	for len(received) < 2 {
+		select {
+		case msg := <-messages:
+			received = append(received, msg)
+		case <-time.After(time.Second):
+			t.Fatalf("等待日志通知超时, 已收到: %+v", received)
+		}
+	}
+
+	select {
+	case msg := <-messages:
+		t.Fatalf("不应该收到更多日志: %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	info := received[0]
+	if info.Level != LoggingLevelInfo || info.Logger != "worker" {
+		t.Errorf("日志级别或名称错误: %+v", info)
+	}
+	data, _ := json.Marshal(info.Data)
+	if string(data) != `{"job":"build","message":"started","step":1}` {
+		t.Errorf("日志数据错误: %s", data)
+	}
+
+	critical := received[1]
+	if critical.Level != LoggingLevelCritical {
+		t.Errorf("日志级别错误: 期望 critical, 得到 %s", critical.Level)
+	}
+	data, _ = json.Marshal(critical.Data)
+	if string(data) != `{"job":"build","message":"failed","result":{"error":"disk full"}}` {
+		t.Errorf("日志数据错误: %s", data)
+	}
+}
+
+// 测试 MCP 日志 - 无效的日志级别
+func TestLogging_SetLevelInvalid(t *testing.T) {
+	server := NewStdioServer(nil, nil)
+	ctx := contextWithSession(context.Background(), newSession(nil))
+
+	response := server.core.handleRequest(ctx, Request{
+		JsonRPC: "2.0",
+		Method:  "logging/setLevel",
+		Params:  map[string]interface{}{"level": "verbose"},
+		ID:      1,
+	}, server.lookup)
+
+	if response.Error == nil || response.Error.Code != InvalidParams {
+		t.Errorf("应该返回 InvalidParams 错误, 得到 %+v", response)
+	}
+}
+
+// 测试 MCP 日志 - initialize 响应中声明 logging 能力
+func TestLogging_Capability(t *testing.T) {
+	server := NewStdioServer(nil, nil, WithServerInfo("test-server", "1.0.0"))
+
+	response := server.handleRequest(Request{
+		JsonRPC: "2.0",
+		Method:  "initialize",
+		Params:  map[string]interface{}{"protocolVersion": "2024-11-05"},
+		ID:      1,
+	})
+
+	result, ok := response.Result.(map[string]interface{})
+	if !ok {
+		t.Fatalf("initialize 响应错误: %+v", response)
+	}
+	if result["protocolVersion"] != "2024-11-05" {
+		t.Errorf("协议版本错误: %v", result["protocolVersion"])
+	}
+	capabilities, _ := result["capabilities"].(map[string]interface{})
+	if _, ok := capabilities["logging"]; !ok {
+		t.Errorf("应该声明 logging 能力: %v", capabilities)
+	}
+	if result["serverInfo"] != (Implementation{Name: "test-server", Version: "1.0.0"}) {
+		t.Errorf("服务器信息错误: %v", result["serverInfo"])
+	}
+}
@@ -0,0 +1,573 @@
+package gomcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NamespaceSeparator 分隔 Manager 目录中名称的服务器前缀与原始名称，例如 github__create_issue
+const NamespaceSeparator = "__"
+
+// catalogRefreshTimeout 是收到 list_changed 通知后重新获取列表的最长时间
+const catalogRefreshTimeout = 30 * time.Second
+
+// ManagedTool 是 Manager 目录中的一个工具，Name 为带服务器前缀的名称
+type ManagedTool struct {
+	Tool
+	Server       string // 提供工具的服务器
+	OriginalName string // 工具在服务器上的名称
+}
+
+// ManagedPrompt 是 Manager 目录中的一个提示词模板，Name 为带服务器前缀的名称
+type ManagedPrompt struct {
+	Prompt
+	Server       string
+	OriginalName string
+}
+
+// ManagedResource 是 Manager 目录中的一个资源，Name 为带服务器前缀的名称，URI 保持不变
+type ManagedResource struct {
+	Resource
+	Server       string
+	OriginalName string
+}
+
+// ManagerOption Manager 的可选配置
+type ManagerOption func(*managerOptions)
+
+type managerOptions struct {
+	clientOptions   []ClientOption
+	catalogHandlers []func(server string)
+	logger          *slog.Logger
+}
+
+// WithManagerClientOptions 设置连接服务器时使用的客户端配置
+func WithManagerClientOptions(opts ...ClientOption) ManagerOption {
+	return func(o *managerOptions) {
+		o.clientOptions = append(o.clientOptions, opts...)
+	}
+}
+
+// WithCatalogHandler 注册目录变化的回调，server 为目录发生变化的服务器。
+// 回调在更新目录的 goroutine 中同步调用，不应长时间阻塞
+func WithCatalogHandler(handler func(server string)) ManagerOption {
+	return func(o *managerOptions) {
+		o.catalogHandlers = append(o.catalogHandlers, handler)
+	}
+}
+
+// WithManagerLogger 设置 Manager 使用的 logger，未设置时使用 slog.Default()
+func WithManagerLogger(logger *slog.Logger) ManagerOption {
+	return func(o *managerOptions) {
+		o.logger = logger
+	}
+}
+
+func (o *managerOptions) log() *slog.Logger {
+	if o.logger == nil {
+		return slog.Default()
+	}
+	return o.logger
+}
+
+// Manager 同时连接配置中所有启用的服务器，维护它们的工具、提示词与资源的合并目录，
+// 并将调用转发给对应的服务器。目录中的名称为 server__name 形式，服务器发送 list_changed
+// 通知时重新获取对应的列表，连接断开的服务器从目录中移除。Manager 不会重连服务器，需要重启时使用 Supervisor
+type Manager struct {
+	config *Config
+	opts   managerOptions
+
+	mu      sync.RWMutex // 保护下面的字段以及各服务器的状态
+	servers map[string]*managedServer
+	started bool
+	closed  bool
+}
+
+// managedServer 是 Manager 连接的一个服务器
+type managedServer struct {
+	name      string
+	refreshMu sync.Mutex // 串行获取列表，避免旧的结果覆盖新的结果
+
+	client     Client
+	err        error // 连接失败或断开的原因
+	tools      []ManagedTool
+	prompts    []ManagedPrompt
+	resources  []ManagedResource
+	pending    map[listKind]bool // 收到 list_changed 通知、等待重新获取的列表
+	refreshing bool              // 正在后台处理 pending 中的列表
+}
+
+// NewManager 创建一个管理 config 中服务器的 Manager
+func NewManager(config *Config, opts ...ManagerOption) *Manager {
+	var o managerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Manager{
+		config:  config,
+		opts:    o,
+		servers: make(map[string]*managedServer),
+	}
+}
+
+// Start 并发连接配置中所有启用的服务器并获取它们的目录，ctx 控制连接与握手的过程。
+// 部分服务器连接失败时返回这些错误，其余服务器仍然可用；名称中包含 NamespaceSeparator 的服务器
+// 无法在目录中区分，不会被连接
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	if m.started {
+		m.mu.Unlock()
+		return errors.New("manager already started")
+	}
+	m.started = true
+
+	var servers []*managedServer
+	var invalid []error
+	for name, config := range m.config.MCPServers {
+		if config.Disabled {
+			continue
+		}
+		server := &managedServer{name: name}
+		m.servers[name] = server
+		if strings.Contains(name, NamespaceSeparator) {
+			server.err = fmt.Errorf("server name %s must not contain %q", name, NamespaceSeparator)
+			invalid = append(invalid, server.err)
+			continue
+		}
+		servers = append(servers, server)
+	}
+	m.mu.Unlock()
+	sort.Slice(servers, func(i, j int) bool { return servers[i].name < servers[j].name })
+	sort.Slice(invalid, func(i, j int) bool { return invalid[i].Error() < invalid[j].Error() })
+
+	errs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		i, server := i, server
+		wg.Add(1)
+		go safe(m.opts.log(), func() {
+			defer wg.Done()
+			errs[i] = m.connect(ctx, server)
+		})()
+	}
+	wg.Wait()
+	return errors.Join(append(invalid, errs...)...)
+}
+
+// connect 连接一个服务器并获取它的目录
+func (m *Manager) connect(ctx context.Context, server *managedServer) error {
+	opts := m.opts.clientOptions[:len(m.opts.clientOptions):len(m.opts.clientOptions)]
+	for _, kind := range []listKind{listTools, listPrompts, listResources} {
+		kind := kind
+		opts = append(opts, WithNotificationHandler("notifications/"+string(kind)+"/list_changed",
+			func(method string, params map[string]interface{}) {
+				m.listChanged(server, kind)
+			}))
+	}
+
+	client, err := m.config.Connect(ctx, server.name, opts...)
+	if err == nil {
+		m.mu.Lock()
+		if m.closed {
+			err = errors.New("manager closed")
+		} else {
+			server.client = client
+		}
+		m.mu.Unlock()
+	}
+	if err == nil {
+		err = m.refresh(ctx, server, listTools, listPrompts, listResources)
+	}
+	if err != nil {
+		if client != nil {
+			_ = client.Close()
+		}
+		m.mu.Lock()
+		server.client = nil
+		server.err = err
+		m.mu.Unlock()
+		m.opts.log().Warn("failed to connect to server", slog.String("server", server.name), slog.Any("error", err))
+		return err
+	}
+
+	if done := clientDone(client); done != nil {
+		go safe(m.opts.log(), func() {
+			<-done
+			m.disconnected(server, client)
+		})()
+	}
+	return nil
+}
+
+// listChanged 处理服务器的 list_changed 通知，在客户端的通知 goroutine 中调用。
+// 列表在后台重新获取，获取期间收到的通知合并为获取结束后的一次获取
+func (m *Manager) listChanged(server *managedServer, kind listKind) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if server.pending == nil {
+		server.pending = make(map[listKind]bool)
+	}
+	server.pending[kind] = true
+	if server.refreshing {
+		return
+	}
+	server.refreshing = true
+	go safe(m.opts.log(), func() {
+		m.handleListChanged(server)
+	})()
+}
+
+// handleListChanged 重新获取 pending 中的列表，直到没有新的通知
+func (m *Manager) handleListChanged(server *managedServer) {
+	for {
+		m.mu.Lock()
+		var kinds []listKind
+		for _, kind := range []listKind{listTools, listPrompts, listResources} {
+			if server.pending[kind] {
+				kinds = append(kinds, kind)
+			}
+		}
+		clear(server.pending)
+		if len(kinds) == 0 {
+			server.refreshing = false
+			m.mu.Unlock()
+			return
+		}
+		m.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), catalogRefreshTimeout)
+		err := m.refresh(ctx, server, kinds...)
+		cancel()
+		if err != nil {
+			m.opts.log().Warn("failed to refresh catalog",
+				slog.String("server", server.name),
+				slog.Any("lists", kinds),
+				slog.Any("error", err),
+			)
+		}
+	}
+}
+
+// refresh 重新获取服务器的指定列表并更新目录，服务器不支持的列表视为空
+func (m *Manager) refresh(ctx context.Context, server *managedServer, kinds ...listKind) error {
+	server.refreshMu.Lock()
+	defer server.refreshMu.Unlock()
+
+	m.mu.RLock()
+	client := server.client
+	m.mu.RUnlock()
+	if client == nil {
+		// 握手完成之前收到的通知由连接后的第一次获取覆盖
+		return nil
+	}
+
+	var tools []ManagedTool
+	var prompts []ManagedPrompt
+	var resources []ManagedResource
+	for _, kind := range kinds {
+		var err error
+		switch kind {
+		case listTools:
+			tools, err = fetchTools(ctx, client, server.name)
+		case listPrompts:
+			prompts, err = fetchPrompts(ctx, client, server.name)
+		case listResources:
+			resources, err = fetchResources(ctx, client, server.name)
+		}
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) && rpcErr.Code == MethodNotFound {
+			err = nil
+		}
+		if err != nil {
+			m.mu.RLock()
+			current := server.client == client
+			m.mu.RUnlock()
+			if !current {
+				// 获取期间连接已经断开或者 Manager 已经关闭
+				return nil
+			}
+			return fmt.Errorf("failed to list %s of server %s: %w", kind, server.name, err)
+		}
+	}
+
+	logger := m.opts.log().With(slog.String("server", server.name))
+	tools = dropDuplicates(logger, listTools, tools, func(tool ManagedTool) string { return tool.Name })
+	prompts = dropDuplicates(logger, listPrompts, prompts, func(prompt ManagedPrompt) string { return prompt.Name })
+	resources = dropDuplicates(logger, listResources, resources, func(resource ManagedResource) string { return resource.Name })
+
+	m.mu.Lock()
+	if server.client != client {
+		// 获取期间连接已经断开或者 Manager 已经关闭
+		m.mu.Unlock()
+		return nil
+	}
+	for _, kind := range kinds {
+		switch kind {
+		case listTools:
+			server.tools = tools
+		case listPrompts:
+			server.prompts = prompts
+		case listResources:
+			server.resources = resources
+		}
+	}
+	m.mu.Unlock()
+
+	m.emit(server.name)
+	return nil
+}
+
+// dropDuplicates 去掉服务器返回的重名条目，只保留第一个，并记录被丢弃的名称
+func dropDuplicates[T any](logger *slog.Logger, kind listKind, items []T, name func(T) string) []T {
+	seen := make(map[string]bool, len(items))
+	unique := items[:0:0]
+	for _, item := range items {
+		if seen[name(item)] {
+			logger.Warn("server returned a duplicate name", slog.String("list", string(kind)), slog.String("name", name(item)))
+			continue
+		}
+		seen[name(item)] = true
+		unique = append(unique, item)
+	}
+	return unique
+}
+
+// disconnected 在客户端断开后将服务器从目录中移除
+func (m *Manager) disconnected(server *managedServer, client Client) {
+	m.mu.Lock()
+	if server.client != client {
+		m.mu.Unlock()
+		return
+	}
+	server.client = nil
+	server.err = errServerDisconnected
+	server.tools, server.prompts, server.resources = nil, nil, nil
+	m.mu.Unlock()
+
+	_ = client.Close()
+	m.opts.log().Warn("server disconnected", slog.String("server", server.name))
+	m.emit(server.name)
+}
+
+func (m *Manager) emit(server string) {
+	for _, handler := range m.opts.catalogHandlers {
+		handler(server)
+	}
+}
+
+// Close 关闭所有服务器的连接，并等待 stdio 服务器的子进程退出
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	var clients []Client
+	for _, server := range m.servers {
+		if server.client != nil {
+			clients = append(clients, server.client)
+		}
+		server.client = nil
+		server.tools, server.prompts, server.resources = nil, nil, nil
+	}
+	m.mu.Unlock()
+
+	errs := make([]error, len(clients))
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		i, client := i, client
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = client.Close()
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Client 返回已连接的服务器的客户端，服务器未连接时返回连接失败或断开的原因
+func (m *Manager) Client(name string) (Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	server, exists := m.servers[name]
+	switch {
+	case !exists:
+		return nil, fmt.Errorf("server %s is not managed", name)
+	case server.client != nil:
+		return server.client, nil
+	case server.err != nil:
+		return nil, fmt.Errorf("server %s is not connected: %w", name, server.err)
+	default:
+		return nil, fmt.Errorf("server %s is not connected", name)
+	}
+}
+
+// Tools 返回所有已连接服务器的工具，按名称排序
+func (m *Manager) Tools() []ManagedTool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var tools []ManagedTool
+	for _, server := range m.servers {
+		tools = append(tools, server.tools...)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
+// Prompts 返回所有已连接服务器的提示词模板，按名称排序
+func (m *Manager) Prompts() []ManagedPrompt {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var prompts []ManagedPrompt
+	for _, server := range m.servers {
+		prompts = append(prompts, server.prompts...)
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+	return prompts
+}
+
+// Resources 返回所有已连接服务器的资源，按名称排序
+func (m *Manager) Resources() []ManagedResource {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var resources []ManagedResource
+	for _, server := range m.servers {
+		resources = append(resources, server.resources...)
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Name < resources[j].Name })
+	return resources
+}
+
+// CallTool 调用目录中名为 name（server__tool 形式）的工具
+func (m *Manager) CallTool(ctx context.Context, name string, arguments map[string]interface{}) (*CallToolResult, error) {
+	client, original, err := m.route(name, func(server *managedServer) (string, bool) {
+		for _, tool := range server.tools {
+			if tool.Name == name {
+				return tool.OriginalName, true
+			}
+		}
+		return "", false
+	})
+	if err != nil {
+		return nil, fmt.Errorf("tool %w", err)
+	}
+	return CallTool(ctx, client, original, arguments)
+}
+
+// GetPrompt 展开目录中名为 name（server__prompt 形式）的提示词模板
+func (m *Manager) GetPrompt(ctx context.Context, name string, arguments map[string]string) (*GetPromptResult, error) {
+	client, original, err := m.route(name, func(server *managedServer) (string, bool) {
+		for _, prompt := range server.prompts {
+			if prompt.Name == name {
+				return prompt.OriginalName, true
+			}
+		}
+		return "", false
+	})
+	if err != nil {
+		return nil, fmt.Errorf("prompt %w", err)
+	}
+	return GetPrompt(ctx, client, original, arguments)
+}
+
+// ReadResource 从提供 uri 的服务器读取资源。多个服务器提供同一个 URI 时返回错误，
+// 这时可以通过 Client 获取指定服务器的客户端读取
+func (m *Manager) ReadResource(ctx context.Context, uri string) (*ReadResourceResult, error) {
+	client, _, err := m.route(uri, func(server *managedServer) (string, bool) {
+		for _, resource := range server.resources {
+			if resource.URI == uri {
+				return uri, true
+			}
+		}
+		return "", false
+	})
+	if err != nil {
+		return nil, fmt.Errorf("resource %w", err)
+	}
+	return ReadResource(ctx, client, uri)
+}
+
+// route 在已连接服务器的目录中查找条目，返回所属服务器的客户端与条目在服务器上的名称。
+// 多个服务器都有该条目时返回错误，而不是任选其中一个
+func (m *Manager) route(name string, find func(server *managedServer) (string, bool)) (Client, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.servers))
+	for serverName := range m.servers {
+		names = append(names, serverName)
+	}
+	sort.Strings(names)
+
+	var (
+		client   Client
+		original string
+		matched  []string
+	)
+	for _, serverName := range names {
+		server := m.servers[serverName]
+		if name, ok := find(server); ok && server.client != nil {
+			client, original = server.client, name
+			matched = append(matched, serverName)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return nil, "", fmt.Errorf("not found: %s", name)
+	case 1:
+		return client, original, nil
+	default:
+		return nil, "", fmt.Errorf("%s is provided by multiple servers: %s", name, strings.Join(matched, ", "))
+	}
+}
+
+// namespaced 返回带服务器前缀的名称
+func namespaced(server, name string) string {
+	return server + NamespaceSeparator + name
+}
+
+// fetchTools 获取服务器的工具并加上服务器前缀
+func fetchTools(ctx context.Context, client Client, server string) ([]ManagedTool, error) {
+	tools, err := ListTools(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	managed := make([]ManagedTool, len(tools))
+	for i, tool := range tools {
+		managed[i] = ManagedTool{Tool: tool, Server: server, OriginalName: tool.Name}
+		managed[i].Name = namespaced(server, tool.Name)
+	}
+	return managed, nil
+}
+
+// fetchPrompts 获取服务器的提示词模板并加上服务器前缀
+func fetchPrompts(ctx context.Context, client Client, server string) ([]ManagedPrompt, error) {
+	prompts, err := ListPrompts(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	managed := make([]ManagedPrompt, len(prompts))
+	for i, prompt := range prompts {
+		managed[i] = ManagedPrompt{Prompt: prompt, Server: server, OriginalName: prompt.Name}
+		managed[i].Name = namespaced(server, prompt.Name)
+	}
+	return managed, nil
+}
+
+// fetchResources 获取服务器的资源并加上服务器前缀
+func fetchResources(ctx context.Context, client Client, server string) ([]ManagedResource, error) {
+	resources, err := ListResources(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	managed := make([]ManagedResource, len(resources))
+	for i, resource := range resources {
+		managed[i] = ManagedResource{Resource: resource, Server: server, OriginalName: resource.Name}
+		managed[i].Name = namespaced(server, resource.Name)
+	}
+	return managed, nil
+}
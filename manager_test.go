@@ -0,0 +1,214 @@
+package gomcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 测试 Manager - 合并多个服务器的目录，按名称转发调用，并在列表变化或连接断开时更新目录
+func TestManager(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "manager_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	newServer := func(name string) Server {
+		server := NewUnixServer(filepath.Join(tempDir, name+".sock"))
+		server.RegisterTool(Tool{Name: "echo"}, func(ctx context.Context, arguments map[string]interface{}) (*CallToolResult, error) {
+			text, _ := arguments["text"].(string)
+			return &CallToolResult{Content: []Content{TextContent(name + ": " + text)}}, nil
+		})
+		if err := server.Start(); err != nil {
+			t.Fatalf("启动服务器失败: %v", err)
+		}
+		t.Cleanup(func() { server.Stop() })
+		return server
+	}
+	alpha := newServer("alpha")
+	alpha.RegisterPrompt(Prompt{Name: "greet"}, func(ctx context.Context, arguments map[string]string) (*GetPromptResult, error) {
+		return &GetPromptResult{Messages: []PromptMessage{{Role: "user", Content: TextContent("hello " + arguments["name"])}}}, nil
+	})
+	beta := newServer("beta")
+	beta.RegisterResource(Resource{URI: "file:///readme.md", Name: "readme"}, func(ctx context.Context, uri string) (*ReadResourceResult, error) {
+		return &ReadResourceResult{Contents: []ResourceContents{{URI: uri, Text: "readme"}}}, nil
+	})
+
+	config := &Config{MCPServers: map[string]ServerConfig{
+		"alpha":    {SocketPath: filepath.Join(tempDir, "alpha.sock")},
+		"beta":     {SocketPath: filepath.Join(tempDir, "beta.sock")},
+		"broken":   {SocketPath: filepath.Join(tempDir, "missing.sock")},
+		"disabled": {SocketPath: filepath.Join(tempDir, "alpha.sock"), Disabled: true},
+	}}
+	changes := make(chan string, 10)
+	manager := NewManager(config, WithCatalogHandler(func(server string) { changes <- server }))
+	defer manager.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 部分服务器连接失败时返回错误，其余服务器仍然可用
+	if err := manager.Start(ctx); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("应该返回 broken 服务器的连接错误, 得到 %v", err)
+	}
+	if _, err := manager.Client("broken"); err == nil {
+		t.Error("连接失败的服务器不应该有客户端")
+	}
+	if _, err := manager.Client("disabled"); err == nil {
+		t.Error("禁用的服务器不应该被连接")
+	}
+
+	toolNames := func() []string {
+		var names []string
+		for _, tool := range manager.Tools() {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+	if names, expected := toolNames(), []string{"alpha__echo", "beta__echo"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("工具目录错误: 期望 %v, 得到 %v", expected, names)
+	}
+	if prompts := manager.Prompts(); len(prompts) != 1 || prompts[0].Name != "alpha__greet" || prompts[0].OriginalName != "greet" {
+		t.Errorf("提示词目录错误: %+v", prompts)
+	}
+	if resources := manager.Resources(); len(resources) != 1 || resources[0].Name != "beta__readme" || resources[0].Server != "beta" {
+		t.Errorf("资源目录错误: %+v", resources)
+	}
+
+	// 调用被转发给对应的服务器
+	result, err := manager.CallTool(ctx, "beta__echo", map[string]interface{}{"text": "hi"})
+	if err != nil {
+		t.Fatalf("调用工具失败: %v", err)
+	}
+	if text := result.Content[0].Text; text != "beta: hi" {
+		t.Errorf("工具结果错误: 期望 %q, 得到 %q", "beta: hi", text)
+	}
+	prompt, err := manager.GetPrompt(ctx, "alpha__greet", map[string]string{"name": "gomcp"})
+	if err != nil {
+		t.Fatalf("获取提示词失败: %v", err)
+	}
+	if text := prompt.Messages[0].Content.Text; text != "hello gomcp" {
+		t.Errorf("提示词结果错误: 期望 %q, 得到 %q", "hello gomcp", text)
+	}
+	resource, err := manager.ReadResource(ctx, "file:///readme.md")
+	if err != nil {
+		t.Fatalf("读取资源失败: %v", err)
+	}
+	if text := resource.Contents[0].Text; text != "readme" {
+		t.Errorf("资源内容错误: 期望 %q, 得到 %q", "readme", text)
+	}
+	if _, err := manager.CallTool(ctx, "echo", nil); err == nil {
+		t.Error("不带服务器前缀的名称应该找不到工具")
+	}
+
+	waitForChange := func(server string) {
+		t.Helper()
+		for {
+			select {
+			case changed := <-changes:
+				if changed == server {
+					return
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("等待 %s 的目录变化超时", server)
+			}
+		}
+	}
+
+	// 服务器的工具列表变化后重新获取目录，先清空启动时的目录变化
+	for len(changes) > 0 {
+		<-changes
+	}
+	alpha.RegisterTool(Tool{Name: "added"}, func(ctx context.Context, arguments map[string]interface{}) (*CallToolResult, error) {
+		return &CallToolResult{Content: []Content{TextContent("added")}}, nil
+	})
+	waitForChange("alpha")
+	if names, expected := toolNames(), []string{"alpha__added", "alpha__echo", "beta__echo"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("工具目录错误: 期望 %v, 得到 %v", expected, names)
+	}
+	if _, err := manager.CallTool(ctx, "alpha__added", nil); err != nil {
+		t.Errorf("调用新增的工具失败: %v", err)
+	}
+
+	// 连接断开的服务器从目录中移除
+	beta.Stop()
+	waitForChange("beta")
+	if names, expected := toolNames(), []string{"alpha__added", "alpha__echo"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("工具目录错误: 期望 %v, 得到 %v", expected, names)
+	}
+	if _, err := manager.Client("beta"); err == nil {
+		t.Error("连接断开的服务器不应该有客户端")
+	}
+
+	if err := manager.Close(); err != nil {
+		t.Errorf("关闭 Manager 失败: %v", err)
+	}
+	if tools := manager.Tools(); len(tools) != 0 {
+		t.Errorf("关闭后目录应该为空: %+v", tools)
+	}
+}
+
+// 测试 Manager - 拒绝包含分隔符的服务器名称，报告重名的条目，并获取分页返回的列表
+func TestManager_Collisions(t *testing.T) {
+	tempDir := t.TempDir()
+	newServer := func(name string, tools ...map[string]interface{}) {
+		server := NewUnixServer(filepath.Join(tempDir, name+".sock"))
+		server.RegisterResource(Resource{URI: "file:///shared", Name: "shared"}, func(ctx context.Context, uri string) (*ReadResourceResult, error) {
+			return &ReadResourceResult{Contents: []ResourceContents{{URI: uri, Text: name}}}, nil
+		})
+		// 按 cursor 依次返回 tools 中的每一页
+		server.RegisterHandler("tools/list", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			page := 0
+			if cursor, ok := params["cursor"].(string); ok {
+				fmt.Sscanf(cursor, "%d", &page)
+			}
+			return tools[page], nil
+		})
+		if err := server.Start(); err != nil {
+			t.Fatalf("启动服务器失败: %v", err)
+		}
+		t.Cleanup(func() { server.Stop() })
+	}
+	newServer("one", map[string]interface{}{"tools": []Tool{{Name: "dup"}, {Name: "dup"}}})
+	newServer("two",
+		map[string]interface{}{"tools": []Tool{{Name: "first"}}, "nextCursor": "1"},
+		map[string]interface{}{"tools": []Tool{{Name: "second"}}},
+	)
+
+	config := &Config{MCPServers: map[string]ServerConfig{
+		"one":      {SocketPath: filepath.Join(tempDir, "one.sock")},
+		"two":      {SocketPath: filepath.Join(tempDir, "two.sock")},
+		"bad__one": {SocketPath: filepath.Join(tempDir, "one.sock")},
+	}}
+	manager := NewManager(config)
+	defer manager.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := manager.Start(ctx); err == nil || !strings.Contains(err.Error(), "bad__one") {
+		t.Errorf("应该拒绝包含分隔符的服务器名称, 得到 %v", err)
+	}
+	if _, err := manager.Client("bad__one"); err == nil {
+		t.Error("名称包含分隔符的服务器不应该被连接")
+	}
+
+	// 服务器内重名的工具只保留一个，分页返回的工具全部获取
+	var names []string
+	for _, tool := range manager.Tools() {
+		names = append(names, tool.Name)
+	}
+	if expected := []string{"one__dup", "two__first", "two__second"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("工具目录错误: 期望 %v, 得到 %v", expected, names)
+	}
+
+	// 多个服务器提供同一个 URI 时报告冲突
+	if _, err := manager.ReadResource(ctx, "file:///shared"); err == nil || !strings.Contains(err.Error(), "one, two") {
+		t.Errorf("应该报告资源冲突, 得到 %v", err)
+	}
+}
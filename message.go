@@ -0,0 +1,132 @@
+package gomcp
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+)
+
+// Notification 定义了 JSON-RPC 通知结构体，通知没有 id，接收方不会响应
+type Notification struct {
+	JsonRPC string                 `json:"jsonrpc"`
+	Method  string                 `json:"method"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+// message 是从连接中读取的一条 JSON-RPC 消息，可能是请求、通知或响应
+type message struct {
+	JsonRPC string                 `json:"jsonrpc"`
+	ID      *int                   `json:"id,omitempty"`
+	Method  string                 `json:"method,omitempty"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+	Result  json.RawMessage        `json:"result,omitempty"`
+	Error   *Error                 `json:"error,omitempty"`
+}
+
+// isNotification 判断消息是否为通知，通知不需要响应
+func (m *message) isNotification() bool {
+	return m.Method != "" && m.ID == nil
+}
+
+// isResponse 判断消息是否为响应
+func (m *message) isResponse() bool {
+	return m.Method == ""
+}
+
+// id 返回消息的 id，没有 id 时返回 0
+func (m *message) id() int {
+	if m.ID == nil {
+		return 0
+	}
+	return *m.ID
+}
+
+// request 将消息转换为请求
+func (m *message) request() Request {
+	return Request{
+		JsonRPC: m.JsonRPC,
+		Method:  m.Method,
+		Params:  m.Params,
+		ID:      m.id(),
+	}
+}
+
+// pendingCalls 管理已发出但尚未收到响应的请求，请求 id 在每个 pendingCalls 内独立分配
+type pendingCalls struct {
+	lastID atomic.Int64
+
+	mu        sync.Mutex // 保护下面的字段
+	pending   map[int]chan *message
+	abandoned map[int]struct{}
+}
+
+// roundTrip 为请求分配 id，通过 write 发送请求并等待对应的响应，ctx 同时控制发送与等待。
+// closed 被关闭时返回 closeErr() 的结果，ctx 结束后到达的响应会被丢弃
+func (p *pendingCalls) roundTrip(ctx context.Context, request *Request, write func(ctx context.Context, v any) error, closed <-chan struct{}, closeErr func() error) (json.RawMessage, error) {
+	request.ID = p.nextID()
+	ch := make(chan *message, 1)
+
+	p.mu.Lock()
+	if p.pending == nil {
+		p.pending = make(map[int]chan *message)
+		p.abandoned = make(map[int]struct{})
+	}
+	p.pending[request.ID] = ch
+	p.mu.Unlock()
+
+	if err := write(ctx, request); err != nil {
+		p.remove(request.ID, false)
+		return nil, &sendError{err: err}
+	}
+
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return nil, msg.Error
+		}
+		return msg.Result, nil
+	case <-closed:
+		p.remove(request.ID, false)
+		return nil, closeErr()
+	case <-ctx.Done():
+		p.remove(request.ID, true)
+		return nil, ctx.Err()
+	}
+}
+
+// nextID 分配一个新的请求 id
+func (p *pendingCalls) nextID() int {
+	return int(p.lastID.Add(1))
+}
+
+// remove 移除等待中的请求，abandon 为 true 时之后到达的响应会被丢弃
+func (p *pendingCalls) remove(id int, abandon bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.pending[id]; !exists {
+		return
+	}
+	delete(p.pending, id)
+	if abandon {
+		p.abandoned[id] = struct{}{}
+	}
+}
+
+// deliver 将响应交给等待它的请求，响应不属于任何已发出的请求时返回 false
+func (p *pendingCalls) deliver(msg *message) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := msg.id()
+	if ch, exists := p.pending[id]; exists {
+		delete(p.pending, id)
+		ch <- msg
+		return true
+	}
+	if _, exists := p.abandoned[id]; exists {
+		delete(p.abandoned, id)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package gomcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// PromptArgument 描述提示词模板的一个参数
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	// Complete 为参数值提供自动补全，为空时补全结果为空列表
+	Complete CompletionFunc `json:"-"`
+}
+
+// Prompt 描述服务器提供的一个提示词模板
+type Prompt struct {
+	Name        string           `json:"name"`
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// argument 返回指定名称的参数
+func (p *Prompt) argument(name string) (*PromptArgument, bool) {
+	for i := range p.Arguments {
+		if p.Arguments[i].Name == name {
+			return &p.Arguments[i], true
+		}
+	}
+	return nil, false
+}
+
+// PromptMessage 是提示词展开后的一条消息，Role 为 user 或 assistant
+type PromptMessage struct {
+	Role    string  `json:"role"`
+	Content Content `json:"content"`
+}
+
+// GetPromptResult 是 prompts/get 请求的结果
+type GetPromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// PromptHandler 根据客户端填写的参数展开提示词，必填参数已经由服务器检查
+type PromptHandler func(ctx context.Context, arguments map[string]string) (*GetPromptResult, error)
+
+// promptEntry 是已注册的提示词及其处理器
+type promptEntry struct {
+	prompt  Prompt
+	handler PromptHandler
+}
+
+// registerPrompt 注册提示词，同名提示词会被替换，并通知已连接的客户端
+func (c *serverCore) registerPrompt(prompt Prompt, handler PromptHandler) {
+	c.mu.Lock()
+	if c.prompts == nil {
+		c.prompts = make(map[string]*promptEntry)
+	}
+	c.prompts[prompt.Name] = &promptEntry{prompt: prompt, handler: handler}
+	c.mu.Unlock()
+
+	c.notifyListChanged(listPrompts)
+}
+
+// removePrompt 移除提示词并通知已连接的客户端，提示词不存在时返回 false
+func (c *serverCore) removePrompt(name string) bool {
+	c.mu.Lock()
+	_, exists := c.prompts[name]
+	delete(c.prompts, name)
+	c.mu.Unlock()
+
+	if exists {
+		c.notifyListChanged(listPrompts)
+	}
+	return exists
+}
+
+// prompt 返回指定名称的提示词
+func (c *serverCore) prompt(name string) (*promptEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, exists := c.prompts[name]
+	return entry, exists
+}
+
+// listPrompts 处理 prompts/list 请求，按名称排序返回所有提示词
+func (c *serverCore) listPrompts(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	c.mu.RLock()
+	prompts := make([]Prompt, 0, len(c.prompts))
+	for _, entry := range c.prompts {
+		prompts = append(prompts, entry.prompt)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+	return map[string]interface{}{"prompts": prompts}, nil
+}
+
+// getPrompt 处理 prompts/get 请求
+func (c *serverCore) getPrompt(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	var request struct {
+		Name      string            `json:"name"`
+		Arguments map[string]string `json:"arguments"`
+	}
+	if err := remarshal(params, &request); err != nil {
+		return nil, NewError(InvalidParams, fmt.Sprintf("invalid prompt request: %v", err), nil)
+	}
+
+	entry, exists := c.prompt(request.Name)
+	if !exists {
+		return nil, NewError(InvalidParams, fmt.Sprintf("Prompt not found: %s", request.Name), nil)
+	}
+	for _, argument := range entry.prompt.Arguments {
+		if argument.Required && request.Arguments[argument.Name] == "" {
+			return nil, NewError(InvalidParams, fmt.Sprintf("Missing required argument: %s", argument.Name), nil)
+		}
+	}
+	if request.Arguments == nil {
+		request.Arguments = map[string]string{}
+	}
+	result, err := entry.handler(ctx, request.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = &GetPromptResult{}
+	}
+	if result.Messages == nil {
+		result.Messages = []PromptMessage{}
+	}
+	return result, nil
+}
+
+// ListPrompts 获取服务器提供的所有提示词模板，服务器分页返回时依次获取所有页
+func ListPrompts(ctx context.Context, client Client) ([]Prompt, error) {
+	return listAll[Prompt](ctx, client, "prompts/list", "prompts")
+}
+
+// GetPrompt 使用 arguments 展开服务器上的提示词模板
+func GetPrompt(ctx context.Context, client Client, name string, arguments map[string]string) (*GetPromptResult, error) {
+	raw, err := client.Call(ctx, "prompts/get", map[string]interface{}{
+		"name":      name,
+		"arguments": arguments,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result GetPromptResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal prompt result: %w", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,292 @@
+package gomcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Resource 描述服务器提供的一个固定 URI 的资源
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceTemplate 描述一组通过 URI 模板（RFC 6570）访问的资源，
+// 支持 {var} 与 {+var} 两种变量，前者不匹配 "/"
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+	// Complete 为模板变量提供自动补全，key 为变量名
+	Complete map[string]CompletionFunc `json:"-"`
+}
+
+// ResourceContents 是资源的内容，文本资源使用 Text，二进制资源使用 base64 编码的 Blob
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// ReadResourceResult 是 resources/read 请求的结果
+type ReadResourceResult struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
+// ResourceHandler 读取固定 URI 的资源
+type ResourceHandler func(ctx context.Context, uri string) (*ReadResourceResult, error)
+
+// ResourceTemplateHandler 读取匹配模板的资源，variables 为从 uri 中解析出的模板变量
+type ResourceTemplateHandler func(ctx context.Context, uri string, variables map[string]string) (*ReadResourceResult, error)
+
+// resourceEntry 是已注册的资源及其处理器
+type resourceEntry struct {
+	resource Resource
+	handler  ResourceHandler
+}
+
+// resourceTemplateEntry 是已注册的资源模板及其处理器
+type resourceTemplateEntry struct {
+	template ResourceTemplate
+	pattern  *uriTemplate
+	handler  ResourceTemplateHandler
+}
+
+// registerResource 注册资源，相同 URI 的资源会被替换，并通知已连接的客户端
+func (c *serverCore) registerResource(resource Resource, handler ResourceHandler) {
+	c.mu.Lock()
+	if c.resources == nil {
+		c.resources = make(map[string]*resourceEntry)
+	}
+	c.resources[resource.URI] = &resourceEntry{resource: resource, handler: handler}
+	c.mu.Unlock()
+
+	c.notifyListChanged(listResources)
+}
+
+// removeResource 移除资源并通知已连接的客户端，资源不存在时返回 false
+func (c *serverCore) removeResource(uri string) bool {
+	c.mu.Lock()
+	_, exists := c.resources[uri]
+	delete(c.resources, uri)
+	c.mu.Unlock()
+
+	if exists {
+		c.notifyListChanged(listResources)
+	}
+	return exists
+}
+
+// registerResourceTemplate 注册资源模板，相同 URITemplate 的模板会被替换，并通知已连接的客户端。
+// 模板格式错误属于编程错误，直接 panic
+func (c *serverCore) registerResourceTemplate(template ResourceTemplate, handler ResourceTemplateHandler) {
+	pattern, err := parseURITemplate(template.URITemplate)
+	if err != nil {
+		panic(err)
+	}
+
+	entry := &resourceTemplateEntry{template: template, pattern: pattern, handler: handler}
+
+	c.mu.Lock()
+	replaced := false
+	for i, existing := range c.resourceTemplates {
+		if existing.template.URITemplate == template.URITemplate {
+			c.resourceTemplates[i], replaced = entry, true
+			break
+		}
+	}
+	if !replaced {
+		c.resourceTemplates = append(c.resourceTemplates, entry)
+	}
+	c.mu.Unlock()
+
+	c.notifyListChanged(listResources)
+}
+
+// removeResourceTemplate 移除资源模板并通知已连接的客户端，模板不存在时返回 false
+func (c *serverCore) removeResourceTemplate(uriTemplate string) bool {
+	c.mu.Lock()
+	index := slices.IndexFunc(c.resourceTemplates, func(entry *resourceTemplateEntry) bool {
+		return entry.template.URITemplate == uriTemplate
+	})
+	if index >= 0 {
+		c.resourceTemplates = slices.Delete(slices.Clone(c.resourceTemplates), index, index+1)
+	}
+	c.mu.Unlock()
+
+	if index < 0 {
+		return false
+	}
+	c.notifyListChanged(listResources)
+	return true
+}
+
+// resourceTemplate 返回指定 URITemplate 的资源模板
+func (c *serverCore) resourceTemplate(uriTemplate string) (*resourceTemplateEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, entry := range c.resourceTemplates {
+		if entry.template.URITemplate == uriTemplate {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// listResources 处理 resources/list 请求，按 URI 排序返回所有固定资源
+func (c *serverCore) listResources(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	c.mu.RLock()
+	resources := make([]Resource, 0, len(c.resources))
+	for _, entry := range c.resources {
+		resources = append(resources, entry.resource)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(resources, func(i, j int) bool { return resources[i].URI < resources[j].URI })
+	return map[string]interface{}{"resources": resources}, nil
+}
+
+// listResourceTemplates 处理 resources/templates/list 请求，按注册顺序返回所有资源模板
+func (c *serverCore) listResourceTemplates(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	c.mu.RLock()
+	templates := make([]ResourceTemplate, 0, len(c.resourceTemplates))
+	for _, entry := range c.resourceTemplates {
+		templates = append(templates, entry.template)
+	}
+	c.mu.RUnlock()
+
+	return map[string]interface{}{"resourceTemplates": templates}, nil
+}
+
+// readResource 处理 resources/read 请求，优先读取 URI 完全相同的固定资源，
+// 否则由第一个匹配 uri 的模板读取
+func (c *serverCore) readResource(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	uri, _ := params["uri"].(string)
+	if uri == "" {
+		return nil, NewError(InvalidParams, "missing resource uri", nil)
+	}
+
+	c.mu.RLock()
+	resource, exists := c.resources[uri]
+	templates := c.resourceTemplates
+	c.mu.RUnlock()
+
+	var (
+		result *ReadResourceResult
+		err    error
+	)
+	if exists {
+		result, err = resource.handler(ctx, uri)
+	} else {
+		matched := false
+		for _, entry := range templates {
+			if variables, ok := entry.pattern.match(uri); ok {
+				result, err = entry.handler(ctx, uri, variables)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, NewError(ResourceNotFound, "Resource not found", map[string]interface{}{"uri": uri})
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = &ReadResourceResult{}
+	}
+	if result.Contents == nil {
+		result.Contents = []ResourceContents{}
+	}
+	return result, nil
+}
+
+// ListResources 获取服务器提供的所有固定资源，服务器分页返回时依次获取所有页
+func ListResources(ctx context.Context, client Client) ([]Resource, error) {
+	return listAll[Resource](ctx, client, "resources/list", "resources")
+}
+
+// ReadResource 读取服务器上 uri 对应的资源
+func ReadResource(ctx context.Context, client Client, uri string) (*ReadResourceResult, error) {
+	raw, err := client.Call(ctx, "resources/read", map[string]interface{}{"uri": uri})
+	if err != nil {
+		return nil, err
+	}
+
+	var result ReadResourceResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal resource contents: %w", err)
+	}
+	return &result, nil
+}
+
+// uriTemplate 是解析后的 URI 模板
+type uriTemplate struct {
+	re   *regexp.Regexp
+	vars []string
+}
+
+// parseURITemplate 将 URI 模板转换为正则表达式
+func parseURITemplate(template string) (*uriTemplate, error) {
+	var (
+		pattern strings.Builder
+		vars    []string
+	)
+	pattern.WriteString("^")
+	rest := template
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			pattern.WriteString(regexp.QuoteMeta(rest))
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("invalid uri template %q: unclosed variable", template)
+		}
+		pattern.WriteString(regexp.QuoteMeta(rest[:start]))
+
+		name, value := rest[start+1:start+end], "([^/]+)"
+		if strings.HasPrefix(name, "+") {
+			name, value = name[1:], "(.+)"
+		}
+		if name == "" || strings.ContainsAny(name, "{,?#&/.;") {
+			return nil, fmt.Errorf("invalid uri template %q: unsupported variable %q", template, rest[start+1:start+end])
+		}
+		pattern.WriteString(value)
+		vars = append(vars, name)
+		rest = rest[start+end+1:]
+	}
+	pattern.WriteString("$")
+
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid uri template %q: %w", template, err)
+	}
+	return &uriTemplate{re: re, vars: vars}, nil
+}
+
+// match 检查 uri 是否匹配模板并返回模板变量
+func (t *uriTemplate) match(uri string) (map[string]string, bool) {
+	matches := t.re.FindStringSubmatch(uri)
+	if matches == nil {
+		return nil, false
+	}
+	variables := make(map[string]string, len(t.vars))
+	for i, name := range t.vars {
+		variables[name] = matches[i+1]
+	}
+	return variables, true
+}
@@ -0,0 +1,133 @@
+package gomcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Root 是宿主共享给服务器的一个根目录，URI 通常为 file:// 形式
+type Root struct {
+	URI  string `json:"uri"`
+	Name string `json:"name,omitempty"`
+}
+
+// Contains 判断本地路径 path 是否位于 file:// 根目录之内
+func (r Root) Contains(path string) bool {
+	u, err := url.Parse(r.URI)
+	if err != nil || u.Scheme != "file" {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Clean(u.Path), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Roots 是宿主声明的根目录列表，可以被多个客户端共享。
+// 列表变化时会向所有使用它的客户端连接发送 notifications/roots/list_changed
+type Roots struct {
+	mu          sync.RWMutex // 保护下面的字段
+	roots       []Root
+	subscribers map[*clientCore]struct{}
+}
+
+// NewRoots 创建一个根目录列表
+func NewRoots(roots ...Root) *Roots {
+	return &Roots{
+		roots:       roots,
+		subscribers: make(map[*clientCore]struct{}),
+	}
+}
+
+// List 返回当前的根目录列表
+func (r *Roots) List() []Root {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Root{}, r.roots...)
+}
+
+// Set 替换根目录列表，并通知所有使用该列表的客户端连接
+func (r *Roots) Set(roots ...Root) {
+	r.mu.Lock()
+	r.roots = roots
+	subscribers := make([]*clientCore, 0, len(r.subscribers))
+	for c := range r.subscribers {
+		subscribers = append(subscribers, c)
+	}
+	r.mu.Unlock()
+
+	for _, c := range subscribers {
+		_ = c.notify("notifications/roots/list_changed", nil)
+	}
+}
+
+func (r *Roots) subscribe(c *clientCore) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribers[c] = struct{}{}
+}
+
+func (r *Roots) unsubscribe(c *clientCore) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.subscribers, c)
+}
+
+// WithRoots 让客户端向服务器提供根目录列表，客户端会响应 roots/list 请求，
+// 并在 roots.Set 被调用时通知服务器。roots 为 nil 时视为空列表
+func WithRoots(roots *Roots) ClientOption {
+	if roots == nil {
+		roots = NewRoots()
+	}
+	return func(o *clientOptions) {
+		o.roots = roots
+		WithRequestHandler("roots/list", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			return map[string]interface{}{"roots": roots.List()}, nil
+		})(o)
+	}
+}
+
+// ListRoots 返回客户端共享给当前会话的根目录列表。
+// 结果会被缓存，直到客户端发送 notifications/roots/list_changed
+func (s *Session) ListRoots(ctx context.Context) ([]Root, error) {
+	s.mu.RLock()
+	roots, cached, generation := s.roots, s.rootsCached, s.rootsGeneration
+	s.mu.RUnlock()
+	if cached {
+		return append([]Root{}, roots...), nil
+	}
+
+	raw, err := s.Call(ctx, "roots/list", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Roots []Root `json:"roots"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal roots: %w", err)
+	}
+
+	s.mu.Lock()
+	if s.rootsGeneration == generation {
+		// 请求期间收到了变更通知时不缓存结果，下次查询重新获取
+		s.roots, s.rootsCached = result.Roots, true
+	}
+	s.mu.Unlock()
+	return append([]Root{}, result.Roots...), nil
+}
+
+// invalidateRoots 清除缓存的根目录列表
+func (s *Session) invalidateRoots() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roots, s.rootsCached = nil, false
+	s.rootsGeneration++
+}
@@ -0,0 +1,171 @@
+package gomcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 测试根目录 - 服务器查询客户端的根目录并在列表变化后重新获取
+func TestRoots_ListAndChange(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "roots_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "test.sock")
+	server := NewUnixServer(socketPath)
+
+	changed := make(chan struct{}, 1)
+	server.RegisterHandler("notifications/roots/list_changed", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		changed <- struct{}{}
+		return nil, nil
+	})
+	server.RegisterHandler("roots", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return SessionFromContext(ctx).ListRoots(ctx)
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	var listCalls int
+	roots := NewRoots(Root{URI: "file:///home/user/project", Name: "project"})
+	client, err := NewUnixClient(socketPath,
+		WithRoots(roots),
+		WithMessageInterceptor(func(msg map[string]interface{}) {
+			if msg["method"] == "roots/list" {
+				listCalls++
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 第二次查询使用缓存
+	for i := 0; i < 2; i++ {
+		result, err := client.Call(ctx, "roots", nil)
+		if err != nil {
+			t.Fatalf("调用失败: %v", err)
+		}
+		if string(result) != `[{"uri":"file:///home/user/project","name":"project"}]` {
+			t.Errorf("根目录列表错误: %s", result)
+		}
+	}
+	if listCalls != 1 {
+		t.Errorf("roots/list 应该只被请求一次, 实际 %d 次", listCalls)
+	}
+
+	// 修改根目录后服务器收到通知并重新获取
+	roots.Set(Root{URI: "file:///tmp/other"})
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("服务器应该收到 notifications/roots/list_changed")
+	}
+
+	result, err := client.Call(ctx, "roots", nil)
+	if err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	if string(result) != `[{"uri":"file:///tmp/other"}]` {
+		t.Errorf("根目录列表错误: %s", result)
+	}
+	if listCalls != 2 {
+		t.Errorf("列表变化后应该重新请求 roots/list, 实际 %d 次", listCalls)
+	}
+}
+
+// 测试根目录 - 请求期间列表发生变化时不缓存旧的结果，WithRoots(nil) 视为空列表
+func TestRoots_ChangeDuringList(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "roots_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "test.sock")
+	server := NewUnixServer(socketPath)
+	server.RegisterHandler("roots", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return SessionFromContext(ctx).ListRoots(ctx)
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	roots := NewRoots(Root{URI: "file:///old"})
+	client, err := NewUnixClient(socketPath,
+		WithRoots(roots),
+		// 先发出变更通知，再返回变更之前的列表
+		WithRequestHandler("roots/list", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			previous := roots.List()
+			roots.Set(Root{URI: "file:///new"})
+			return map[string]interface{}{"roots": previous}, nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := client.Call(ctx, "roots", nil); err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	result, err := client.Call(ctx, "roots", nil)
+	if err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	if string(result) != `[{"uri":"file:///new"}]` {
+		t.Errorf("不应该缓存变更之前的根目录列表: %s", result)
+	}
+
+	nilClient, err := NewUnixClient(socketPath, WithRoots(nil))
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	defer nilClient.Close()
+	result, err = nilClient.Call(ctx, "roots", nil)
+	if err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	if string(result) != `[]` {
+		t.Errorf("WithRoots(nil) 应该返回空列表: %s", result)
+	}
+}
+
+// 测试根目录 - 判断路径是否位于根目录之内
+func TestRoot_Contains(t *testing.T) {
+	root := Root{URI: "file:///home/user/project"}
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/home/user/project", true},
+		{"/home/user/project/src/main.go", true},
+		{"/home/user/project/../secret", false},
+		{"/home/user/project-other", false},
+		{"/etc/passwd", false},
+	}
+	for _, tt := range tests {
+		if got := root.Contains(tt.path); got != tt.expected {
+			t.Errorf("Contains(%s) 错误: 期望 %v, 得到 %v", tt.path, tt.expected, got)
+		}
+	}
+
+	if (Root{URI: "https://example.com"}).Contains("/home") {
+		t.Error("非 file:// 根目录不应该包含任何本地路径")
+	}
+}
@@ -0,0 +1,81 @@
+package gomcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// SamplingMessage 是采样请求中的一条对话消息，Role 为 user 或 assistant
+type SamplingMessage struct {
+	Role    string  `json:"role"`
+	Content Content `json:"content"`
+}
+
+// ModelHint 建议客户端使用的模型名称，客户端可以映射到自己可用的模型
+type ModelHint struct {
+	Name string `json:"name,omitempty"`
+}
+
+// ModelPreferences 服务器对模型选择的偏好，各优先级取值范围为 0 到 1
+type ModelPreferences struct {
+	Hints                []ModelHint `json:"hints,omitempty"`
+	CostPriority         float64     `json:"costPriority,omitempty"`
+	SpeedPriority        float64     `json:"speedPriority,omitempty"`
+	IntelligencePriority float64     `json:"intelligencePriority,omitempty"`
+}
+
+// SamplingRequest 是 sampling/createMessage 请求的参数
+type SamplingRequest struct {
+	Messages         []SamplingMessage      `json:"messages"`
+	ModelPreferences *ModelPreferences      `json:"modelPreferences,omitempty"`
+	SystemPrompt     string                 `json:"systemPrompt,omitempty"`
+	IncludeContext   string                 `json:"includeContext,omitempty"` // none、thisServer 或 allServers
+	Temperature      *float64               `json:"temperature,omitempty"`
+	MaxTokens        int                    `json:"maxTokens"`
+	StopSequences    []string               `json:"stopSequences,omitempty"`
+	Metadata         map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// SamplingResult 是 sampling/createMessage 请求的结果
+type SamplingResult struct {
+	Role       string  `json:"role"`
+	Content    Content `json:"content"`
+	Model      string  `json:"model"`
+	StopReason string  `json:"stopReason,omitempty"`
+}
+
+// SamplingHandler 处理服务器发来的采样请求，通常由宿主调用 LLM 生成回复。
+// 宿主拒绝请求时可以返回 *Error
+type SamplingHandler func(ctx context.Context, request *SamplingRequest) (*SamplingResult, error)
+
+// CreateMessage 请求客户端通过 LLM 生成一条消息，阻塞直到客户端返回结果。
+// 客户端需要通过 WithSamplingHandler 支持采样
+func (s *Session) CreateMessage(ctx context.Context, request SamplingRequest) (*SamplingResult, error) {
+	var params map[string]interface{}
+	if err := remarshal(request, &params); err != nil {
+		return nil, fmt.Errorf("failed to marshal sampling request: %w", err)
+	}
+
+	raw, err := s.Call(ctx, "sampling/createMessage", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var result SamplingResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sampling result: %w", err)
+	}
+	return &result, nil
+}
+
+// WithSamplingHandler 设置处理服务器采样请求（sampling/createMessage）的处理器
+func WithSamplingHandler(handler SamplingHandler) ClientOption {
+	return WithRequestHandler("sampling/createMessage", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		var request SamplingRequest
+		if err := remarshal(params, &request); err != nil {
+			return nil, NewError(InvalidParams, fmt.Sprintf("invalid sampling request: %v", err), nil)
+		}
+		return handler(ctx, &request)
+	})
+}
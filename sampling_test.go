@@ -0,0 +1,124 @@
+package gomcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 测试采样 - 处理器在处理请求的过程中向客户端发起 sampling/createMessage
+func TestSampling_CreateMessage(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "sampling_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "test.sock")
+	server := NewUnixServer(socketPath)
+	server.RegisterHandler("summarize", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		result, err := SessionFromContext(ctx).CreateMessage(ctx, SamplingRequest{
+			Messages: []SamplingMessage{
+				{Role: "user", Content: TextContent(params["text"].(string))},
+			},
+			SystemPrompt: "Summarize the text",
+			MaxTokens:    100,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result.Content.Text, nil
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("客户端支持采样", func(t *testing.T) {
+		var received *SamplingRequest
+		client, err := NewUnixClient(socketPath, WithSamplingHandler(func(ctx context.Context, request *SamplingRequest) (*SamplingResult, error) {
+			received = request
+			return &SamplingResult{
+				Role:    "assistant",
+				Content: TextContent("summary of " + request.Messages[0].Content.Text),
+				Model:   "test-model",
+			}, nil
+		}))
+		if err != nil {
+			t.Fatalf("创建客户端失败: %v", err)
+		}
+		defer client.Close()
+
+		result, err := client.Call(ctx, "summarize", map[string]interface{}{"text": "long text"})
+		if err != nil {
+			t.Fatalf("调用失败: %v", err)
+		}
+		if string(result) != `"summary of long text"` {
+			t.Errorf("响应中的result字段错误: 得到 %s", result)
+		}
+		if received == nil || received.SystemPrompt != "Summarize the text" || received.MaxTokens != 100 {
+			t.Errorf("客户端收到的采样请求错误: %+v", received)
+		}
+	})
+
+	t.Run("客户端拒绝采样", func(t *testing.T) {
+		client, err := NewUnixClient(socketPath, WithSamplingHandler(func(ctx context.Context, request *SamplingRequest) (*SamplingResult, error) {
+			return nil, NewError(InvalidRequest, "user rejected sampling", nil)
+		}))
+		if err != nil {
+			t.Fatalf("创建客户端失败: %v", err)
+		}
+		defer client.Close()
+
+		_, err = client.Call(ctx, "summarize", map[string]interface{}{"text": "long text"})
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) || rpcErr.Code != InvalidRequest || rpcErr.Message != "user rejected sampling" {
+			t.Errorf("应该返回客户端的错误, 得到 %v", err)
+		}
+	})
+
+	t.Run("客户端不支持采样", func(t *testing.T) {
+		client, err := NewUnixClient(socketPath)
+		if err != nil {
+			t.Fatalf("创建客户端失败: %v", err)
+		}
+		defer client.Close()
+
+		_, err = client.Call(ctx, "summarize", map[string]interface{}{"text": "long text"})
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) || rpcErr.Code != MethodNotFound {
+			t.Errorf("应该返回 MethodNotFound 错误, 得到 %v", err)
+		}
+	})
+}
+
+// 测试采样 - 会话结束后等待中的请求返回 ErrClosed
+func TestSampling_SessionClosed(t *testing.T) {
+	session := newSession(newStreamTransport(strings.NewReader(""), io.Discard, nil))
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := session.CreateMessage(context.Background(), SamplingRequest{MaxTokens: 10})
+		errCh <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	session.close()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("应该返回 ErrClosed, 得到 %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("会话结束后请求应该立即返回")
+	}
+}
@@ -1,22 +1,533 @@
 package gomcp
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"net"
+	"os"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
 )
 
-// Server 定义了 MCP 服务器的接口
+// Server 定义了 MCP 服务器的接口。工具、提示词与资源可以在 Start 之后注册或移除，
+// 服务器会向已完成初始化的会话广播对应的 list_changed 通知
 type Server interface {
 	// Start 启动服务器
 	Start() error
-	// Stop 停止服务器
+	// Stop 立即停止服务器并断开所有连接，不等待处理中的请求，可以重复调用
 	Stop() error
-	// RegisterHandler 注册一个请求处理器，用于处理指定的方法名
+	// Serve 启动服务器并阻塞，直到 ctx 结束、服务器被关闭或发生无法恢复的错误，返回导致停止的错误：
+	// ctx 结束时立即停止服务器并返回 ctx 的错误，调用 Stop 或 Shutdown 时返回 ErrServerClosed
+	Serve(ctx context.Context) error
+	// Shutdown 优雅地停止服务器：拒绝新的连接与请求，等待处理中的请求结束后断开所有连接。
+	// ctx 结束时不再等待，直接断开连接并返回 ctx 的错误。可以重复或并发调用
+	Shutdown(ctx context.Context) error
+	// RegisterHandler 注册一个请求处理器，用于处理指定的方法名。
+	// 替换 tools/list 等列表方法的处理器时会发送对应的 list_changed 通知
 	RegisterHandler(method string, handler RequestHandler)
+	// RemoveHandler 移除指定方法名的处理器，处理器不存在时返回 false
+	RemoveHandler(method string) bool
+	// Use 注册中间件，中间件会包装所有请求与通知的处理过程
+	Use(middlewares ...Middleware)
+	// RegisterTool 注册一个工具，同名工具会被替换
+	RegisterTool(tool Tool, handler ToolHandler)
+	// RemoveTool 移除一个工具，工具不存在时返回 false
+	RemoveTool(name string) bool
+	// RegisterPrompt 注册一个提示词模板，同名提示词会被替换
+	RegisterPrompt(prompt Prompt, handler PromptHandler)
+	// RemovePrompt 移除一个提示词模板，提示词不存在时返回 false
+	RemovePrompt(name string) bool
+	// RegisterResource 注册一个资源，相同 URI 的资源会被替换
+	RegisterResource(resource Resource, handler ResourceHandler)
+	// RemoveResource 移除一个资源，资源不存在时返回 false
+	RemoveResource(uri string) bool
+	// RegisterResourceTemplate 注册一个资源模板，模板格式错误时 panic
+	RegisterResourceTemplate(template ResourceTemplate, handler ResourceTemplateHandler)
+	// RemoveResourceTemplate 移除一个资源模板，模板不存在时返回 false
+	RemoveResourceTemplate(uriTemplate string) bool
+	// Sessions 返回所有活跃的会话，按建立时间排序
+	Sessions() []*Session
+	// CloseSession 断开指定 id 的会话，会话不存在时返回 false
+	CloseSession(id string) bool
 }
 
 // RequestHandler 是处理特定请求方法的函数类型
-type RequestHandler func(params map[string]interface{}) (interface{}, error)
+type RequestHandler func(ctx context.Context, params map[string]interface{}) (interface{}, error)
+
+// HandlerFunc 是中间件链中的处理函数，method 为当前请求或通知的方法名
+type HandlerFunc func(ctx context.Context, method string, params map[string]interface{}) (interface{}, error)
+
+// Middleware 包装 HandlerFunc，用于在所有处理器外层添加鉴权、日志、指标等通用逻辑。
+// 中间件可以修改 ctx 和 params 后调用 next，也可以不调用 next 直接返回结果或错误
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// PanicHandler 处理器发生 panic 时的回调，rec 为 recover 得到的值，stack 为 panic 时的调用栈
+type PanicHandler func(method string, rec any, stack []byte)
+
+// ServerOption 服务器的可选配置
+type ServerOption func(*serverOptions)
+
+// serverOptions 保存服务器的可选配置，零值可直接使用
+type serverOptions struct {
+	panicHandler PanicHandler
+	middlewares  []Middleware
+	logger       *slog.Logger
+	serverInfo   Implementation
+	heartbeat    heartbeatOptions
+}
+
+// WithPanicHandler 设置处理器 panic 时的回调，未设置时通过 logger 记录堆栈
+func WithPanicHandler(handler PanicHandler) ServerOption {
+	return func(o *serverOptions) {
+		o.panicHandler = handler
+	}
+}
+
+// WithMiddleware 在创建服务器时注册中间件，效果与调用 Use 相同
+func WithMiddleware(middlewares ...Middleware) ServerOption {
+	return func(o *serverOptions) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// WithServerLogger 设置服务器使用的 logger，未设置时使用 slog.Default()。
+// 连接的建立与关闭以 Info 级别记录，请求以 Debug 级别记录，处理失败的请求以 Warn 级别记录，
+// panic 以 Error 级别记录
+func WithServerLogger(logger *slog.Logger) ServerOption {
+	return func(o *serverOptions) {
+		o.logger = logger
+	}
+}
+
+// log 返回服务器使用的 logger
+func (o *serverOptions) log() *slog.Logger {
+	if o.logger == nil {
+		return slog.Default()
+	}
+	return o.logger
+}
+
+func newServerOptions(opts []ServerOption) serverOptions {
+	var o serverOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// serverCore 包含各传输层服务器共享的请求分发逻辑，零值可直接使用
+type serverCore struct {
+	opts              serverOptions
+	mu                sync.RWMutex // 保护下面的字段以及 opts.middlewares 的并发访问
+	tools             map[string]*toolEntry
+	prompts           map[string]*promptEntry
+	resources         map[string]*resourceEntry
+	resourceTemplates []*resourceTemplateEntry
+	sessions          map[*Session]struct{}
+	shuttingDown      bool
+	inflight          int           // 正在处理的请求数
+	drained           chan struct{} // 关闭过程中所有请求处理完成时关闭
+}
+
+// handlerLookup 根据方法名查找处理器，由各传输层服务器提供
+type handlerLookup func(method string) (RequestHandler, bool)
+
+// use 追加中间件，先注册的中间件位于外层
+func (c *serverCore) use(middlewares ...Middleware) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.opts.middlewares = append(c.opts.middlewares, middlewares...)
+}
+
+// handleRequest 处理一个请求并构建响应
+func (c *serverCore) handleRequest(ctx context.Context, request Request, lookup handlerLookup) Response {
+	// 构建基本响应
+	response := Response{
+		JsonRPC: "2.0",
+		ID:      request.ID,
+	}
+
+	start := time.Now()
+	result, err := c.dispatch(ctx, request.Method, request.Params, lookup)
+	if err != nil {
+		response.Error = toError(err)
+	} else {
+		response.Result = result
+	}
+
+	c.logRequest(ctx, "request handled", request, time.Since(start), response.Error)
+	return response
+}
+
+// handleNotification 处理一个通知，通知没有响应，未注册处理器的通知会被忽略
+func (c *serverCore) handleNotification(ctx context.Context, request Request, lookup handlerLookup) {
+	start := time.Now()
+	_, err := c.dispatch(ctx, request.Method, request.Params, lookup)
+
+	var rpcErr *Error
+	if err != nil {
+		rpcErr = toError(err)
+		if rpcErr.Code == MethodNotFound {
+			rpcErr = nil
+		}
+	}
+	c.logRequest(ctx, "notification handled", request, time.Since(start), rpcErr)
+}
+
+// logRequest 记录请求的处理结果，失败的请求使用 Warn 级别
+func (c *serverCore) logRequest(ctx context.Context, msg string, request Request, duration time.Duration, rpcErr *Error) {
+	attrs := []slog.Attr{
+		slog.String("method", request.Method),
+		slog.Int("id", request.ID),
+		slog.Duration("duration", duration),
+	}
+	level := slog.LevelDebug
+	if rpcErr != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.Int("code", int(rpcErr.Code)), slog.String("error", rpcErr.Message))
+	}
+	c.opts.log().LogAttrs(ctx, level, msg, attrs...)
+}
+
+// builtin 返回服务器内置的 MCP 方法处理器，用户通过 RegisterHandler 注册的同名处理器优先
+func (c *serverCore) builtin(method string) (RequestHandler, bool) {
+	switch method {
+	case "initialize":
+		return c.initialize, true
+	case "ping":
+		return ping, true
+	case "logging/setLevel":
+		return c.setLoggingLevel, true
+	case "tools/list":
+		return c.listTools, true
+	case "tools/call":
+		return c.callTool, true
+	case "prompts/list":
+		return c.listPrompts, true
+	case "prompts/get":
+		return c.getPrompt, true
+	case "resources/list":
+		return c.listResources, true
+	case "resources/templates/list":
+		return c.listResourceTemplates, true
+	case "resources/read":
+		return c.readResource, true
+	case "completion/complete":
+		return c.complete, true
+	}
+	return nil, false
+}
+
+// serveSession 持续读取会话中的消息并处理，直到连接关闭、读取失败或 done 被关闭。
+// 请求在独立的 goroutine 中处理，处理器可以在等待客户端响应的同时继续读取消息；
+// 通知按到达顺序依次处理。返回前会取消所有处理器的 ctx 并等待它们结束
+func (c *serverCore) serveSession(session *Session, lookup handlerLookup, done <-chan struct{}) error {
+	if !c.addSession(session) {
+		return ErrServerClosed
+	}
+	ctx, cancel := context.WithCancel(contextWithSession(context.Background(), session))
+	var wg sync.WaitGroup
+	defer func() {
+		c.removeSession(session)
+		session.close()
+		cancel()
+		wg.Wait()
+	}()
+
+	if c.opts.heartbeat.interval > 0 {
+		wg.Add(1)
+		go safe(c.opts.log(), func() {
+			defer wg.Done()
+			c.keepalive(ctx, session)
+		})()
+	}
+
+	for {
+		// 检查是否已关闭
+		select {
+		case <-done:
+			return nil
+		default:
+			// 继续处理
+		}
+
+		data, err := session.transport.readMessage()
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.opts.log().Warn("failed to unmarshal message", slog.Any("error", err))
+			err = session.write(context.Background(), Response{
+				JsonRPC: "2.0",
+				Error:   NewError(ParseError, fmt.Sprintf("Parse Error: %v", err), nil),
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch {
+		case msg.isResponse():
+			// 客户端对服务器请求的响应
+			if !session.calls.deliver(&msg) {
+				c.opts.log().Warn("unexpected response", slog.Int("id", msg.id()))
+			}
+		case msg.isNotification():
+			// 通知不需要响应
+			c.observeNotification(session, &msg)
+			c.handleNotification(ctx, msg.request(), lookup)
+		default:
+			// 服务器关闭过程中拒绝新的请求
+			if !c.beginRequest() {
+				response := Response{
+					JsonRPC: "2.0",
+					ID:      msg.id(),
+					Error:   NewError(InternalError, "Server is shutting down", nil),
+				}
+				c.logRequest(ctx, "request rejected", msg.request(), 0, response.Error)
+				if err := c.reply(session, response); err != nil && !isClosedError(err) {
+					c.opts.log().Warn("failed to write response", slog.String("method", msg.Method), slog.Any("error", err))
+				}
+				continue
+			}
+
+			// 处理请求
+			wg.Add(1)
+			go safe(c.opts.log(), func() {
+				defer wg.Done()
+				defer c.endRequest()
+				if err := c.reply(session, c.handleRequest(ctx, msg.request(), lookup)); err != nil && !isClosedError(err) {
+					c.opts.log().Warn("failed to write response", slog.String("method", msg.Method), slog.Any("error", err))
+				}
+			})()
+		}
+	}
+}
+
+// addSession 记录活跃的会话，用于广播列表变更通知，服务器正在关闭时返回 false
+func (c *serverCore) addSession(session *Session) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.shuttingDown {
+		return false
+	}
+	if c.sessions == nil {
+		c.sessions = make(map[*Session]struct{})
+	}
+	c.sessions[session] = struct{}{}
+	return true
+}
+
+// activeSessions 返回所有活跃的会话，按建立时间排序
+func (c *serverCore) activeSessions() []*Session {
+	c.mu.RLock()
+	sessions := make([]*Session, 0, len(c.sessions))
+	for session := range c.sessions {
+		sessions = append(sessions, session)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].createdAt.Before(sessions[j].createdAt) })
+	return sessions
+}
+
+// closeSession 断开指定 id 的会话
+func (c *serverCore) closeSession(id string) bool {
+	for _, session := range c.activeSessions() {
+		if session.id == id {
+			if err := session.Close(); err != nil {
+				c.opts.log().Warn("failed to close session", slog.String("session", id), slog.Any("error", err))
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// removeSession 移除已结束的会话
+func (c *serverCore) removeSession(session *Session) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.sessions, session)
+}
+
+// beginRequest 登记一个开始处理的请求，服务器正在关闭时返回 false
+func (c *serverCore) beginRequest() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.shuttingDown {
+		return false
+	}
+	c.inflight++
+	return true
+}
+
+// closing 检查服务器是否正在关闭或已经关闭
+func (c *serverCore) closing() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.shuttingDown
+}
+
+// endRequest 登记一个请求处理完成，响应已经写入
+func (c *serverCore) endRequest() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inflight--
+	if c.inflight == 0 && c.drained != nil {
+		close(c.drained)
+		c.drained = nil
+	}
+}
+
+// shutdown 拒绝新的会话与请求，等待处理中的请求结束后断开所有会话，
+// ctx 结束时不再等待并返回 ctx 的错误
+func (c *serverCore) shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	c.shuttingDown = true
+	var drained chan struct{}
+	if c.inflight > 0 {
+		if c.drained == nil {
+			c.drained = make(chan struct{})
+		}
+		drained = c.drained
+	}
+	c.mu.Unlock()
+
+	var err error
+	if drained != nil {
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+
+	for _, session := range c.activeSessions() {
+		if closeErr := session.Close(); closeErr != nil {
+			c.opts.log().Warn("failed to close session", slog.String("session", session.id), slog.Any("error", closeErr))
+		}
+	}
+	return err
+}
+
+// listKind 是支持变更通知的列表类型
+type listKind string
+
+const (
+	listTools     listKind = "tools"
+	listPrompts   listKind = "prompts"
+	listResources listKind = "resources"
+)
+
+// handlerChanged 在 RegisterHandler 或 RemoveHandler 替换了 tools/list 等列表方法的处理器后，
+// 通知已连接的客户端列表可能发生了变化
+func (c *serverCore) handlerChanged(method string) {
+	switch method {
+	case "tools/list":
+		c.notifyListChanged(listTools)
+	case "prompts/list":
+		c.notifyListChanged(listPrompts)
+	case "resources/list", "resources/templates/list":
+		c.notifyListChanged(listResources)
+	}
+}
+
+// notifyListChanged 向所有协商了对应 listChanged 能力的会话发送列表变更通知
+func (c *serverCore) notifyListChanged(kind listKind) {
+	sessions := c.activeSessions()
+	method := "notifications/" + string(kind) + "/list_changed"
+	for _, session := range sessions {
+		if !session.listChangedEnabled(kind) {
+			continue
+		}
+		if err := session.Notify(method, nil); err != nil {
+			c.opts.log().Debug("failed to send list changed notification", slog.String("method", method), slog.Any("error", err))
+		}
+	}
+}
+
+// keepalive 定期 ping 客户端，客户端连续多次未响应时关闭会话的连接
+func (c *serverCore) keepalive(ctx context.Context, session *Session) {
+	if err := c.opts.heartbeat.run(ctx.Done(), session.Ping, c.opts.log()); err != nil {
+		c.opts.log().Warn("closing unresponsive session", slog.String("session", session.id), slog.Any("error", err))
+		_ = session.Close()
+	}
+}
+
+// observeNotification 在分发通知之前更新会话状态，不受用户注册的同名处理器影响
+func (c *serverCore) observeNotification(session *Session, msg *message) {
+	switch msg.Method {
+	case "notifications/roots/list_changed":
+		session.invalidateRoots()
+	}
+}
+
+// reply 向会话写入响应，结果无法编码时改为返回 InternalError
+func (c *serverCore) reply(session *Session, response Response) error {
+	if _, err := json.Marshal(response.Result); err != nil {
+		c.opts.log().Warn("failed to encode response", slog.Int("id", response.ID), slog.Any("error", err))
+		response.Result = nil
+		response.Error = NewError(InternalError, fmt.Sprintf("Encode Error: %v", err), nil)
+	}
+	return session.write(context.Background(), response)
+}
+
+// dispatch 经过中间件链调用 method 对应的处理器
+func (c *serverCore) dispatch(ctx context.Context, method string, params map[string]interface{}, lookup handlerLookup) (interface{}, error) {
+	handler := HandlerFunc(func(ctx context.Context, method string, params map[string]interface{}) (interface{}, error) {
+		h, exists := lookup(method)
+		if !exists {
+			h, exists = c.builtin(method)
+		}
+		if !exists {
+			return nil, NewError(MethodNotFound, fmt.Sprintf("Method not found: %s", method), nil)
+		}
+		return h(ctx, params)
+	})
+
+	c.mu.RLock()
+	for i := len(c.opts.middlewares) - 1; i >= 0; i-- {
+		handler = c.opts.middlewares[i](handler)
+	}
+	c.mu.RUnlock()
+
+	return c.opts.callHandler(ctx, method, handler, params)
+}
+
+// callHandler 调用处理器，处理器发生 panic 时上报堆栈并转换为 InternalError，
+// 保证单个请求的 panic 不会影响所在的连接
+func (o *serverOptions) callHandler(ctx context.Context, method string, handler HandlerFunc, params map[string]interface{}) (result interface{}, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			o.reportPanic(method, rec, debug.Stack())
+			result = nil
+			err = NewError(InternalError, fmt.Sprintf("Method panicked: %s", method), nil)
+		}
+	}()
+	return handler(ctx, method, params)
+}
+
+func (o *serverOptions) reportPanic(method string, rec any, stack []byte) {
+	if o.panicHandler != nil {
+		o.panicHandler(method, rec, stack)
+		return
+	}
+	o.log().Error("handler panic",
+		slog.String("method", method),
+		slog.Any("panic", rec),
+		slog.String("stack", string(stack)),
+	)
+}
 
 // Response mcp server 的响应结构体
 type Response struct {
@@ -28,5 +539,5 @@ type Response struct {
 
 // isClosedError 检查错误是否是由于连接关闭导致的
 func isClosedError(err error) bool {
-	return errors.Is(err, io.EOF)
+	return errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) || errors.Is(err, os.ErrClosed) || errors.Is(err, ErrServerClosed)
 }
@@ -1,9 +1,10 @@
 package gomcp
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"errors"
 	"io"
+	"log/slog"
 	"sync"
 )
 
@@ -14,30 +15,81 @@ type StdioServer struct {
 	done     chan struct{}
 	handlers map[string]RequestHandler
 	mu       sync.RWMutex // 保护 handlers 的并发访问
+	core     serverCore
+	doneOnce sync.Once // 关闭 done
+	err      error     // 导致服务器停止的错误，由 mu 保护
 }
 
 // NewStdioServer 创建一个新的标准输入输出 MCP 服务器
-func NewStdioServer(reader io.Reader, writer io.Writer) *StdioServer {
+func NewStdioServer(reader io.Reader, writer io.Writer, opts ...ServerOption) *StdioServer {
 	return &StdioServer{
 		reader:   reader,
 		writer:   writer,
 		done:     make(chan struct{}),
 		handlers: make(map[string]RequestHandler),
+		core:     serverCore{opts: newServerOptions(opts)},
 	}
 }
 
-// Start 启动服务器
+// Start 启动服务器，服务器停止后不能再次启动
 func (s *StdioServer) Start() error {
-	go Safe(s.handleMessages)()
+	select {
+	case <-s.done:
+		return ErrServerClosed
+	default:
+	}
+	go safe(s.core.opts.log(), s.handleMessages)()
 	return nil
 }
 
-// Stop 停止服务器
+// Serve 启动服务器并阻塞，直到 ctx 结束、服务器被关闭或输入流结束。
+// 输入流正常结束时返回 nil，读取失败时返回对应的错误；ctx 结束时立即停止服务器并返回 ctx 的错误；
+// 调用 Stop 或 Shutdown 时返回 ErrServerClosed
+func (s *StdioServer) Serve(ctx context.Context) error {
+	if err := s.Start(); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		if err := s.Stop(); err != nil {
+			return errors.Join(ctx.Err(), err)
+		}
+		return ctx.Err()
+	case <-s.done:
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		switch {
+		case errors.Is(s.err, io.EOF):
+			return nil
+		case s.err != nil:
+			return s.err
+		default:
+			return ErrServerClosed
+		}
+	}
+}
+
+// Stop 立即停止服务器，不等待处理中的请求，可以重复调用
 func (s *StdioServer) Stop() error {
-	close(s.done)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Shutdown(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
 	return nil
 }
 
+// Shutdown 优雅地停止服务器：拒绝新的请求，等待处理中的请求结束后关闭会话。
+// reader 实现了 io.Closer 时会被关闭以结束读取。ctx 结束时不再等待并返回 ctx 的错误，可以重复调用
+func (s *StdioServer) Shutdown(ctx context.Context) error {
+	err := s.core.shutdown(ctx)
+	s.doneOnce.Do(func() {
+		close(s.done)
+	})
+	return err
+}
+
 // Wait 等待服务器停止
 func (s *StdioServer) Wait() {
 	<-s.done
@@ -46,80 +98,111 @@ func (s *StdioServer) Wait() {
 // RegisterHandler 注册一个方法处理器
 func (s *StdioServer) RegisterHandler(method string, handler RequestHandler) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.handlers[method] = handler
+	s.mu.Unlock()
+	s.core.handlerChanged(method)
 }
 
-func (s *StdioServer) handleMessages() {
-	decoder := json.NewDecoder(s.reader)
-	encoder := json.NewEncoder(s.writer)
-
-	for {
-		select {
-		case <-s.done:
-			return
-		default:
-			var (
-				request  Request
-				response Response
-			)
-			if err := decoder.Decode(&request); err != nil {
-				if !isClosedError(err) {
-					response.Error = &Error{
-						Code:    ParseError,
-						Message: fmt.Sprintf("Parse Error: %v", err),
-					}
-				}
-				continue
-			}
-
-			// 处理请求
-			response = s.handleRequest(request)
-			if err := encoder.Encode(response); err != nil {
-				response.Error = &Error{
-					Code:    ParseError,
-					Message: fmt.Sprintf("Encode Error: %v", err)}
-				continue
-			}
-		}
+// RemoveHandler 移除一个方法处理器，处理器不存在时返回 false
+func (s *StdioServer) RemoveHandler(method string) bool {
+	s.mu.Lock()
+	_, exists := s.handlers[method]
+	delete(s.handlers, method)
+	s.mu.Unlock()
+	if exists {
+		s.core.handlerChanged(method)
 	}
+	return exists
 }
 
-func (s *StdioServer) handleRequest(request Request) Response {
-	// 构建基本响应
-	response := Response{
-		JsonRPC: "2.0",
-		ID:      request.ID,
-	}
+// Use 注册中间件，中间件会包装所有请求与通知的处理过程
+func (s *StdioServer) Use(middlewares ...Middleware) {
+	s.core.use(middlewares...)
+}
 
-	var (
-		method = request.Method
-		params = request.Params
-	)
+// RegisterTool 注册一个工具，同名工具会被替换，并通知已连接的客户端
+func (s *StdioServer) RegisterTool(tool Tool, handler ToolHandler) {
+	s.core.registerTool(tool, handler)
+}
 
-	// 查找处理器
-	s.mu.RLock()
-	handler, exists := s.handlers[method]
-	s.mu.RUnlock()
+// RemoveTool 移除一个工具并通知已连接的客户端
+func (s *StdioServer) RemoveTool(name string) bool {
+	return s.core.removeTool(name)
+}
 
-	if !exists {
-		response.Error = &Error{
-			Code:    MethodNotFound,
-			Message: fmt.Sprintf("Method not found: %s", method),
-		}
-		return response
+// RegisterPrompt 注册一个提示词模板，同名提示词会被替换，并通知已连接的客户端
+func (s *StdioServer) RegisterPrompt(prompt Prompt, handler PromptHandler) {
+	s.core.registerPrompt(prompt, handler)
+}
+
+// RemovePrompt 移除一个提示词模板并通知已连接的客户端
+func (s *StdioServer) RemovePrompt(name string) bool {
+	return s.core.removePrompt(name)
+}
+
+// RegisterResource 注册一个资源，相同 URI 的资源会被替换，并通知已连接的客户端
+func (s *StdioServer) RegisterResource(resource Resource, handler ResourceHandler) {
+	s.core.registerResource(resource, handler)
+}
+
+// RemoveResource 移除一个资源并通知已连接的客户端
+func (s *StdioServer) RemoveResource(uri string) bool {
+	return s.core.removeResource(uri)
+}
+
+// RegisterResourceTemplate 注册一个资源模板，模板格式错误时 panic
+func (s *StdioServer) RegisterResourceTemplate(template ResourceTemplate, handler ResourceTemplateHandler) {
+	s.core.registerResourceTemplate(template, handler)
+}
+
+// RemoveResourceTemplate 移除一个资源模板并通知已连接的客户端
+func (s *StdioServer) RemoveResourceTemplate(uriTemplate string) bool {
+	return s.core.removeResourceTemplate(uriTemplate)
+}
+
+// Sessions 返回所有活跃的会话
+func (s *StdioServer) Sessions() []*Session {
+	return s.core.activeSessions()
+}
+
+// CloseSession 断开指定 id 的会话，会话不存在时返回 false
+func (s *StdioServer) CloseSession(id string) bool {
+	return s.core.closeSession(id)
+}
+
+// handleMessages 处理输入流中的消息，输入流结束或读取失败后停止服务器
+func (s *StdioServer) handleMessages() {
+	// 输入流关闭或格式错误后无法继续读取
+	var closer func() error
+	if c, ok := s.reader.(io.Closer); ok {
+		closer = c.Close
+	}
+	session := newSession(newStreamTransport(s.reader, s.writer, closer))
+	err := s.core.serveSession(session, s.lookup, s.done)
+	if err == nil || s.core.closing() {
+		// 服务器已经停止，读取失败是关闭输入流导致的
+		return
 	}
 
-	// 调用处理器
-	result, err := handler(params)
-	if err != nil {
-		response.Error = &Error{
-			Code:    InternalError,
-			Message: fmt.Sprintf("Method deal failed: %s", err.Error()),
-		}
+	if isClosedError(err) {
+		s.core.opts.log().Info("stdio input closed")
 	} else {
-		response.Result = result
+		s.core.opts.log().Warn("failed to read message", slog.Any("error", err))
 	}
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+	_ = s.Stop()
+}
+
+func (s *StdioServer) handleRequest(request Request) Response {
+	return s.core.handleRequest(context.Background(), request, s.lookup)
+}
 
-	return response
+// lookup 查找方法对应的处理器
+func (s *StdioServer) lookup(method string) (RequestHandler, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	handler, exists := s.handlers[method]
+	return handler, exists
 }
@@ -2,9 +2,12 @@ package gomcp
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"io"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -14,7 +17,7 @@ func TestStdioServer_RegisterHandler(t *testing.T) {
 	server := NewStdioServer(nil, nil)
 
 	// 注册一个处理器
-	testHandler := func(params map[string]interface{}) (interface{}, error) {
+	testHandler := func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 		return "test_result", nil
 	}
 
@@ -30,7 +33,7 @@ func TestStdioServer_RegisterHandler(t *testing.T) {
 	}
 
 	// 测试处理器是否能正常工作
-	result, err := handler(map[string]interface{}{})
+	result, err := handler(context.Background(), map[string]interface{}{})
 	if err != nil {
 		t.Errorf("处理器执行出错: %v", err)
 	}
@@ -114,7 +117,7 @@ func TestStdioServer_HandleRequest_Success(t *testing.T) {
 	server := NewStdioServer(nil, nil)
 
 	// 注册一个测试处理器
-	server.RegisterHandler("test_method", func(params map[string]interface{}) (interface{}, error) {
+	server.RegisterHandler("test_method", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 		return "success_result", nil
 	})
 
@@ -152,7 +155,7 @@ func TestStdioServer_HandleRequest_HandlerError(t *testing.T) {
 	server := NewStdioServer(nil, nil)
 
 	// 注册一个返回错误的处理器
-	server.RegisterHandler("error_method", func(params map[string]interface{}) (interface{}, error) {
+	server.RegisterHandler("error_method", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 		return nil, errors.New("handler error")
 	})
 
@@ -186,11 +189,54 @@ func TestStdioServer_HandleRequest_HandlerError(t *testing.T) {
 	}
 }
 
+// 测试处理请求 - 处理器 panic 的情况
+func TestStdioServer_HandleRequest_Panic(t *testing.T) {
+	var (
+		panicMethod string
+		panicValue  any
+		panicStack  []byte
+	)
+	server := NewStdioServer(nil, nil, WithPanicHandler(func(method string, rec any, stack []byte) {
+		panicMethod, panicValue, panicStack = method, rec, stack
+	}))
+
+	// 注册一个会 panic 的处理器
+	server.RegisterHandler("panic_method", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		panic("boom")
+	})
+
+	response := server.handleRequest(Request{
+		JsonRPC: "2.0",
+		Method:  "panic_method",
+		ID:      4,
+	})
+
+	if response.ID != 4 {
+		t.Errorf("响应中的id字段错误: 期望 4, 得到 %v", response.ID)
+	}
+
+	if response.Error == nil {
+		t.Fatal("响应中应该包含error字段")
+	}
+
+	if response.Error.Code != InternalError {
+		t.Errorf("错误代码错误: 期望 %d, 得到 %d", InternalError, response.Error.Code)
+	}
+
+	if panicMethod != "panic_method" || panicValue != "boom" {
+		t.Errorf("panic 回调参数错误: method=%s, rec=%v", panicMethod, panicValue)
+	}
+
+	if len(panicStack) == 0 {
+		t.Error("panic 回调应该收到调用栈")
+	}
+}
+
 // 测试处理消息 - 模拟输入输出
 func TestStdioServer_HandleMessages(t *testing.T) {
 	// 创建输入和输出缓冲区
 	inputBuffer := bytes.NewBufferString(`{"jsonrpc":"2.0","method":"echo","params":{"message":"hello"},"id":1}`)
-	outputBuffer := &bytes.Buffer{}
+	outputBuffer := &syncBuffer{}
 
 	// 创建服务器并设置输入输出
 	server := &StdioServer{
@@ -201,7 +247,7 @@ func TestStdioServer_HandleMessages(t *testing.T) {
 	}
 
 	// 注册一个回显处理器
-	server.RegisterHandler("echo", func(params map[string]interface{}) (interface{}, error) {
+	server.RegisterHandler("echo", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 		return params["message"], nil
 	})
 
@@ -224,3 +270,98 @@ func TestStdioServer_HandleMessages(t *testing.T) {
 		t.Errorf("输出应该包含id 1, 实际输出: %s", output)
 	}
 }
+
+// 测试处理消息 - 通知经过中间件且不产生响应
+func TestStdioServer_HandleMessages_Notification(t *testing.T) {
+	inputBuffer := bytes.NewBufferString(`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n" +
+		`{"jsonrpc":"2.0","method":"echo","params":{"message":"hello"},"id":2}`)
+	outputBuffer := &syncBuffer{}
+
+	server := NewStdioServer(inputBuffer, outputBuffer)
+
+	var (
+		mu      sync.Mutex
+		methods []string
+	)
+	server.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, method string, params map[string]interface{}) (interface{}, error) {
+			mu.Lock()
+			methods = append(methods, method)
+			mu.Unlock()
+			return next(ctx, method, params)
+		}
+	})
+
+	initialized := make(chan struct{})
+	server.RegisterHandler("notifications/initialized", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		close(initialized)
+		return nil, nil
+	})
+	server.RegisterHandler("echo", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return params["message"], nil
+	})
+
+	go Safe(server.handleMessages)()
+
+	select {
+	case <-initialized:
+	case <-time.After(time.Second):
+		t.Fatal("通知处理器应该被调用")
+	}
+
+	// 等待处理完成
+	time.Sleep(100 * time.Millisecond)
+	server.Stop()
+
+	// 只有请求会产生响应
+	lines := strings.Split(strings.TrimSpace(outputBuffer.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"id":2`) {
+		t.Errorf("输出应该只包含请求的响应, 实际输出: %s", outputBuffer.String())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(methods) != 2 || methods[0] != "notifications/initialized" || methods[1] != "echo" {
+		t.Errorf("通知和请求都应该经过中间件, 得到 %v", methods)
+	}
+}
+
+// syncBuffer 是并发安全的 bytes.Buffer，用于在测试中读取服务器的输出
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// 测试 Serve - 输入流结束时返回 nil，ctx 结束时返回 ctx 的错误
+func TestStdioServer_Serve(t *testing.T) {
+	input := strings.NewReader(`{"jsonrpc":"2.0","method":"ping","id":1}` + "\n")
+	output := &syncBuffer{}
+	server := NewStdioServer(input, output)
+	if err := server.Serve(context.Background()); err != nil {
+		t.Errorf("输入流结束时应该返回 nil, 得到 %v", err)
+	}
+	if !strings.Contains(output.String(), `"id":1`) {
+		t.Errorf("输出应该包含响应, 实际输出: %s", output.String())
+	}
+
+	reader, writer, _ := os.Pipe()
+	defer writer.Close()
+	server = NewStdioServer(reader, io.Discard)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := server.Serve(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("期望 DeadlineExceeded, 得到 %v", err)
+	}
+}
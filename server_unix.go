@@ -1,11 +1,15 @@
 package gomcp
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net"
 	"os"
 	"sync"
+	"syscall"
+	"time"
 )
 
 // UnixServer 实现了基于 Unix Domain Socket 的 MCP 服务器
@@ -14,28 +18,107 @@ type UnixServer struct {
 	listener   net.Listener
 	handlers   map[string]RequestHandler
 	done       chan struct{}
-	mu         sync.RWMutex // 保护 handlers 的并发访问
-	err        error
+	mu         sync.RWMutex // 保护 handlers、listener 与 closing 的并发访问
+	closing    bool         // Shutdown 已经开始，Start 不再保存新的 listener
+	core       serverCore
+	closeOnce  sync.Once // 关闭 listener 并删除 socket 文件
+	doneOnce   sync.Once // 关闭 done
+	err        error     // 导致服务器停止的错误，由 mu 保护
 }
 
 // NewUnixServer 创建一个新的 Unix Domain Socket MCP 服务器
-func NewUnixServer(socketPath string) Server {
+func NewUnixServer(socketPath string, opts ...ServerOption) Server {
 	return &UnixServer{
 		socketPath: socketPath,
 		handlers:   make(map[string]RequestHandler),
 		done:       make(chan struct{}),
+		core:       serverCore{opts: newServerOptions(opts)},
 	}
 }
 
 // RegisterHandler 注册一个请求处理程序
 func (s *UnixServer) RegisterHandler(method string, handler RequestHandler) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.handlers[method] = handler
+	s.mu.Unlock()
+	s.core.handlerChanged(method)
 }
 
-// Start 启动服务器
+// RemoveHandler 移除一个方法处理器，处理器不存在时返回 false
+func (s *UnixServer) RemoveHandler(method string) bool {
+	s.mu.Lock()
+	_, exists := s.handlers[method]
+	delete(s.handlers, method)
+	s.mu.Unlock()
+	if exists {
+		s.core.handlerChanged(method)
+	}
+	return exists
+}
+
+// Use 注册中间件，中间件会包装所有请求与通知的处理过程
+func (s *UnixServer) Use(middlewares ...Middleware) {
+	s.core.use(middlewares...)
+}
+
+// RegisterTool 注册一个工具，同名工具会被替换，并通知已连接的客户端
+func (s *UnixServer) RegisterTool(tool Tool, handler ToolHandler) {
+	s.core.registerTool(tool, handler)
+}
+
+// RemoveTool 移除一个工具并通知已连接的客户端
+func (s *UnixServer) RemoveTool(name string) bool {
+	return s.core.removeTool(name)
+}
+
+// RegisterPrompt 注册一个提示词模板，同名提示词会被替换，并通知已连接的客户端
+func (s *UnixServer) RegisterPrompt(prompt Prompt, handler PromptHandler) {
+	s.core.registerPrompt(prompt, handler)
+}
+
+// RemovePrompt 移除一个提示词模板并通知已连接的客户端
+func (s *UnixServer) RemovePrompt(name string) bool {
+	return s.core.removePrompt(name)
+}
+
+// RegisterResource 注册一个资源，相同 URI 的资源会被替换，并通知已连接的客户端
+func (s *UnixServer) RegisterResource(resource Resource, handler ResourceHandler) {
+	s.core.registerResource(resource, handler)
+}
+
+// RemoveResource 移除一个资源并通知已连接的客户端
+func (s *UnixServer) RemoveResource(uri string) bool {
+	return s.core.removeResource(uri)
+}
+
+// RegisterResourceTemplate 注册一个资源模板，模板格式错误时 panic
+func (s *UnixServer) RegisterResourceTemplate(template ResourceTemplate, handler ResourceTemplateHandler) {
+	s.core.registerResourceTemplate(template, handler)
+}
+
+// RemoveResourceTemplate 移除一个资源模板并通知已连接的客户端
+func (s *UnixServer) RemoveResourceTemplate(uriTemplate string) bool {
+	return s.core.removeResourceTemplate(uriTemplate)
+}
+
+// Sessions 返回所有活跃的会话
+func (s *UnixServer) Sessions() []*Session {
+	return s.core.activeSessions()
+}
+
+// CloseSession 断开指定 id 的会话，会话不存在时返回 false
+func (s *UnixServer) CloseSession(id string) bool {
+	return s.core.closeSession(id)
+}
+
+// Start 启动服务器，服务器停止后不能再次启动
 func (s *UnixServer) Start() error {
+	select {
+	case <-s.done:
+		return ErrServerClosed
+	default:
+	}
+
 	// 确保 socket 文件不存在
 	_ = os.Remove(s.socketPath)
 
@@ -44,22 +127,95 @@ func (s *UnixServer) Start() error {
 	if err != nil {
 		return fmt.Errorf("failed to create socket: %w", err)
 	}
+	s.mu.Lock()
+	if s.closing {
+		// Start 与 Shutdown 并发时 Shutdown 可能已经关闭过 listener，这里自行关闭
+		s.mu.Unlock()
+		_ = listener.Close()
+		_ = os.Remove(s.socketPath)
+		return ErrServerClosed
+	}
 	s.listener = listener
+	s.mu.Unlock()
 
 	// 设置 socket 文件权限
 	if err := os.Chmod(s.socketPath, 0666); err != nil {
 		return fmt.Errorf("failed to set socket permissions: %w", err)
 	}
 
-	go Safe(s.acceptConnections)()
+	s.core.opts.log().Info("unix server listening", slog.String("socket", s.socketPath))
+	go safe(s.core.opts.log(), func() {
+		s.acceptConnections(listener)
+	})()
 	return nil
 }
 
-// Stop 停止服务器
+// Serve 启动服务器并阻塞，直到 ctx 结束、服务器被关闭或接受连接时发生无法恢复的错误。
+// ctx 结束时立即停止服务器并返回 ctx 的错误；调用 Stop 或 Shutdown 时返回 ErrServerClosed；
+// 文件描述符耗尽等临时错误会在退避后重试，不会导致 Serve 返回
+func (s *UnixServer) Serve(ctx context.Context) error {
+	if err := s.Start(); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		if err := s.Stop(); err != nil {
+			return errors.Join(ctx.Err(), err)
+		}
+		return ctx.Err()
+	case <-s.done:
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		if s.err != nil {
+			return s.err
+		}
+		return ErrServerClosed
+	}
+}
+
+// Stop 立即停止服务器：停止接受新连接并断开所有连接，不等待处理中的请求，可以重复调用
 func (s *UnixServer) Stop() error {
-	close(s.done)
-	if s.listener != nil {
-		return s.listener.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Shutdown(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown 优雅地停止服务器：停止接受新连接，拒绝新的请求，等待处理中的请求结束后
+// 断开所有连接并删除 socket 文件。ctx 结束时不再等待，直接断开连接并返回 ctx 的错误。
+// 可以重复或并发调用，Wait 在第一次 Shutdown 完成后返回
+func (s *UnixServer) Shutdown(ctx context.Context) error {
+	var closeErr error
+	s.closeOnce.Do(func() {
+		closeErr = s.closeListener()
+	})
+
+	err := s.core.shutdown(ctx)
+	s.doneOnce.Do(func() {
+		close(s.done)
+	})
+	s.core.opts.log().Info("unix server stopped", slog.String("socket", s.socketPath))
+	return errors.Join(closeErr, err)
+}
+
+// closeListener 停止接受新连接并删除 socket 文件
+func (s *UnixServer) closeListener() error {
+	s.mu.Lock()
+	s.closing = true
+	listener := s.listener
+	s.mu.Unlock()
+	if listener == nil {
+		return nil
+	}
+
+	if err := listener.Close(); err != nil && !isClosedError(err) {
+		return fmt.Errorf("failed to close listener: %w", err)
+	}
+	if err := os.Remove(s.socketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove socket: %w", err)
 	}
 	return nil
 }
@@ -69,22 +225,37 @@ func (s *UnixServer) Wait() {
 	<-s.done
 }
 
-func (s *UnixServer) acceptConnections() {
+// acceptConnections 持续接受新连接。临时错误在退避后重试，其他错误会记录下来并停止服务器
+func (s *UnixServer) acceptConnections(listener net.Listener) {
+	var backoff time.Duration
 	for {
 		select {
 		case <-s.done:
 			return
 		default:
-			conn, err := s.listener.Accept()
+			conn, err := listener.Accept()
 			if err != nil {
-				if isClosedError(err) {
+				if isClosedError(err) || s.core.closing() {
 					return // 服务器已关闭，退出
 				}
-				continue
+				if isTemporaryAcceptError(err) {
+					backoff = min(max(2*backoff, 5*time.Millisecond), time.Second)
+					s.core.opts.log().Warn("failed to accept connection", slog.Any("error", err), slog.Duration("retry", backoff))
+					time.Sleep(backoff)
+					continue
+				}
+
+				s.core.opts.log().Error("unix server failed", slog.Any("error", err))
+				s.mu.Lock()
+				s.err = fmt.Errorf("failed to accept connection: %w", err)
+				s.mu.Unlock()
+				_ = s.Stop()
+				return
 			}
+			backoff = 0
 
 			// 启动新的 goroutine 处理连接
-			go Safe(func() {
+			go safe(s.core.opts.log(), func() {
 				s.handleConnection(conn)
 			})()
 		}
@@ -92,73 +263,40 @@ func (s *UnixServer) acceptConnections() {
 }
 
 func (s *UnixServer) handleConnection(conn net.Conn) {
+	session := newSession(newStreamTransport(conn, conn, conn.Close))
+	logger := s.core.opts.log().With(slog.String("socket", s.socketPath), slog.String("session", session.ID()))
+	logger.Info("connection accepted")
 	defer func() {
 		if err := conn.Close(); err != nil && !isClosedError(err) {
-			s.err = err
+			logger.Warn("failed to close connection", slog.Any("error", err))
 		}
+		logger.Info("connection closed")
 	}()
 
-	decoder := json.NewDecoder(conn)
-	encoder := json.NewEncoder(conn)
-
-	for {
-		// 检查是否已关闭
-		select {
-		case <-s.done:
-			return
-		default:
-			// 继续处理
-		}
-
-		var request Request
-		if err := decoder.Decode(&request); err != nil {
-			if !isClosedError(err) {
-				s.err = err
-			}
-			return
-		}
-
-		// 处理请求
-		response := s.handleRequest(request)
-
-		// 使用互斥锁保护写入操作
-		if err := encoder.Encode(response); err != nil {
-			if !isClosedError(err) {
-				s.err = err
-			}
-			return
-		}
+	if err := s.core.serveSession(session, s.lookup, s.done); err != nil && !isClosedError(err) {
+		logger.Warn("connection failed", slog.Any("error", err))
 	}
 }
 
 func (s *UnixServer) handleRequest(request Request) Response {
-	// 构建基本响应
-	response := Response{
-		JsonRPC: "2.0",
-		ID:      request.ID,
-	}
+	return s.core.handleRequest(context.Background(), request, s.lookup)
+}
 
-	// 查找并执行处理程序
+// lookup 查找方法对应的处理器
+func (s *UnixServer) lookup(method string) (RequestHandler, bool) {
 	s.mu.RLock()
-	handler, exists := s.handlers[request.Method]
-	s.mu.RUnlock()
+	defer s.mu.RUnlock()
+	handler, exists := s.handlers[method]
+	return handler, exists
+}
 
-	if exists {
-		result, err := handler(request.Params)
-		if err != nil {
-			response.Error = &Error{
-				Code:    ParseError,
-				Message: err.Error(),
-			}
-		} else {
-			response.Result = result
-		}
-	} else {
-		response.Error = &Error{
-			Code:    MethodNotFound,
-			Message: fmt.Sprintf("Method not found: %s", request.Method),
-		}
+// isTemporaryAcceptError 检查接受连接时的错误是否可以重试，例如文件描述符耗尽
+func isTemporaryAcceptError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
 	}
-
-	return response
+	return errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EINTR) ||
+		errors.Is(err, syscall.ENOBUFS) || errors.Is(err, syscall.ENOMEM)
 }
@@ -1,11 +1,17 @@
 package gomcp
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -41,7 +47,7 @@ func TestUnixServer_RegisterHandler(t *testing.T) {
 	}
 
 	// 注册一个处理器
-	testHandler := func(params map[string]interface{}) (interface{}, error) {
+	testHandler := func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 		return "test_result", nil
 	}
 
@@ -56,7 +62,7 @@ func TestUnixServer_RegisterHandler(t *testing.T) {
 	}
 
 	// 测试处理器是否能正常工作
-	result, err := handler(map[string]interface{}{})
+	result, err := handler(context.Background(), map[string]interface{}{})
 	if err != nil {
 		t.Errorf("处理器执行出错: %v", err)
 	}
@@ -116,7 +122,7 @@ func TestUnixServer_HandleRequest_Success(t *testing.T) {
 	}
 
 	// 注册一个测试处理器
-	server.RegisterHandler("test_method", func(params map[string]interface{}) (interface{}, error) {
+	server.RegisterHandler("test_method", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 		return "success_result", nil
 	})
 
@@ -158,7 +164,7 @@ func TestUnixServer_HandleRequest_HandlerError(t *testing.T) {
 	}
 
 	// 注册一个返回错误的处理器
-	server.RegisterHandler("error_method", func(params map[string]interface{}) (interface{}, error) {
+	server.RegisterHandler("error_method", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 		return nil, errors.New("handler error")
 	})
 
@@ -186,8 +192,8 @@ func TestUnixServer_HandleRequest_HandlerError(t *testing.T) {
 		t.Fatal("响应中应该包含error字段")
 	}
 
-	if response.Error.Code != ParseError {
-		t.Errorf("错误代码错误: 期望 %d, 得到 %d", ParseError, response.Error.Code)
+	if response.Error.Code != InternalError {
+		t.Errorf("错误代码错误: 期望 %d, 得到 %d", InternalError, response.Error.Code)
 	}
 
 	if response.Error.Message != "handler error" {
@@ -195,6 +201,43 @@ func TestUnixServer_HandleRequest_HandlerError(t *testing.T) {
 	}
 }
 
+// 测试处理请求 - 处理器返回 *Error 的情况
+func TestUnixServer_HandleRequest_RPCError(t *testing.T) {
+	server := &UnixServer{
+		socketPath: "/tmp/test_unix_server.sock",
+		handlers:   make(map[string]RequestHandler),
+		done:       make(chan struct{}),
+	}
+
+	// 注册一个返回 *Error 的处理器，错误被包装过也应该保留错误码
+	server.RegisterHandler("invalid_params", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("wrapped: %w", NewError(InvalidParams, "missing name", map[string]interface{}{"field": "name"}))
+	})
+
+	response := server.handleRequest(Request{
+		JsonRPC: "2.0",
+		Method:  "invalid_params",
+		ID:      5,
+	})
+
+	if response.Error == nil {
+		t.Fatal("响应中应该包含error字段")
+	}
+
+	if response.Error.Code != InvalidParams {
+		t.Errorf("错误代码错误: 期望 %d, 得到 %d", InvalidParams, response.Error.Code)
+	}
+
+	if response.Error.Message != "missing name" {
+		t.Errorf("错误消息错误: 期望 'missing name', 得到 %v", response.Error.Message)
+	}
+
+	data, ok := response.Error.Data.(map[string]interface{})
+	if !ok || data["field"] != "name" {
+		t.Errorf("错误数据错误: 期望 {field: name}, 得到 %v", response.Error.Data)
+	}
+}
+
 // 测试处理请求 - 无效方法类型的情况
 func TestUnixServer_HandleRequest_InvalidMethodType(t *testing.T) {
 	server := &UnixServer{
@@ -304,7 +347,7 @@ func TestUnixServer_ClientServerCommunication(t *testing.T) {
 	server := NewUnixServer(socketPath)
 
 	// 注册一个回显处理器
-	server.RegisterHandler("echo", func(params map[string]interface{}) (interface{}, error) {
+	server.RegisterHandler("echo", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 		return params["message"], nil
 	})
 
@@ -366,3 +409,378 @@ func TestUnixServer_ClientServerCommunication(t *testing.T) {
 		t.Errorf("响应中的result字段错误: 期望 'hello', 得到 %v", response.Result)
 	}
 }
+
+// 测试中间件 - 执行顺序、上下文传递与短路返回
+func TestUnixServer_Middleware(t *testing.T) {
+	type ctxKey struct{}
+	server := NewUnixServer("/tmp/test_unix_server.sock").(*UnixServer)
+
+	var calls []string
+	server.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, method string, params map[string]interface{}) (interface{}, error) {
+			calls = append(calls, "outer:"+method)
+			return next(context.WithValue(ctx, ctxKey{}, "user-1"), method, params)
+		}
+	}, func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, method string, params map[string]interface{}) (interface{}, error) {
+			calls = append(calls, "inner:"+method)
+			if params["token"] != "secret" {
+				return nil, NewError(InvalidRequest, "unauthorized", nil)
+			}
+			return next(ctx, method, params)
+		}
+	})
+
+	server.RegisterHandler("whoami", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return ctx.Value(ctxKey{}), nil
+	})
+
+	// 通过鉴权的请求，处理器能拿到中间件写入上下文的值
+	response := server.handleRequest(Request{
+		JsonRPC: "2.0",
+		Method:  "whoami",
+		Params:  map[string]interface{}{"token": "secret"},
+		ID:      1,
+	})
+	if response.Result != "user-1" {
+		t.Errorf("响应中的result字段错误: 期望 'user-1', 得到 %v", response.Result)
+	}
+
+	if len(calls) != 2 || calls[0] != "outer:whoami" || calls[1] != "inner:whoami" {
+		t.Errorf("中间件执行顺序错误: %v", calls)
+	}
+
+	// 未通过鉴权的请求被中间件拦截
+	response = server.handleRequest(Request{
+		JsonRPC: "2.0",
+		Method:  "whoami",
+		ID:      2,
+	})
+	if response.Error == nil || response.Error.Code != InvalidRequest {
+		t.Errorf("请求应该被中间件拦截, 得到 %+v", response)
+	}
+
+	// 方法不存在时同样经过中间件
+	calls = nil
+	server.handleRequest(Request{
+		JsonRPC: "2.0",
+		Method:  "missing",
+		Params:  map[string]interface{}{"token": "secret"},
+		ID:      3,
+	})
+	if len(calls) != 2 {
+		t.Errorf("方法不存在时也应该经过中间件, 得到 %v", calls)
+	}
+}
+
+// 测试日志 - 请求的方法、id、耗时与错误码被记录
+func TestUnixServer_Logger(t *testing.T) {
+	output := &syncBuffer{}
+	logger := slog.New(slog.NewJSONHandler(output, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	server := NewUnixServer("/tmp/test_unix_server.sock", WithServerLogger(logger)).(*UnixServer)
+
+	server.RegisterHandler("ok", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	server.RegisterHandler("panic", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		panic("boom")
+	})
+
+	server.handleRequest(Request{JsonRPC: "2.0", Method: "ok", ID: 1})
+	server.handleRequest(Request{JsonRPC: "2.0", Method: "panic", ID: 2})
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("解析日志失败: %v", err)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) != 3 {
+		t.Fatalf("日志条数错误: 期望 3, 得到 %d: %s", len(records), output.String())
+	}
+
+	// 成功的请求以 Debug 级别记录
+	if records[0]["level"] != "DEBUG" || records[0]["method"] != "ok" || records[0]["id"] != float64(1) {
+		t.Errorf("成功请求的日志错误: %v", records[0])
+	}
+	if _, ok := records[0]["duration"]; !ok {
+		t.Errorf("日志中应该包含耗时: %v", records[0])
+	}
+
+	// panic 以 Error 级别记录并包含调用栈
+	if records[1]["level"] != "ERROR" || records[1]["panic"] != "boom" || records[1]["stack"] == "" {
+		t.Errorf("panic 的日志错误: %v", records[1])
+	}
+
+	// 失败的请求以 Warn 级别记录并包含错误码
+	if records[2]["level"] != "WARN" || records[2]["code"] != float64(InternalError) {
+		t.Errorf("失败请求的日志错误: %v", records[2])
+	}
+}
+
+// 测试优雅关闭 - 等待处理中的请求完成，拒绝新的请求与连接
+func TestUnixServer_Shutdown(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "unix_server_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "test.sock")
+	server := NewUnixServer(socketPath)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server.RegisterHandler("slow", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		close(started)
+		<-release
+		return "finished", nil
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+
+	client, err := NewUnixClient(socketPath)
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	slowResult := make(chan error, 1)
+	go func() {
+		result, err := client.Call(ctx, "slow", nil)
+		if err == nil && string(result) != `"finished"` {
+			err = fmt.Errorf("结果错误: %s", result)
+		}
+		slowResult <- err
+	}()
+	<-started
+
+	shutdownResult := make(chan error, 1)
+	go func() {
+		shutdownResult <- server.Shutdown(ctx)
+	}()
+
+	// 关闭过程中的新请求被拒绝
+	var rpcErr *Error
+	deadline := time.Now().Add(time.Second)
+	for {
+		err := Ping(ctx, client)
+		if errors.As(err, &rpcErr) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("关闭过程中的请求应该被拒绝, 得到 %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if rpcErr.Code != InternalError {
+		t.Errorf("错误码错误: 期望 %d, 得到 %d", InternalError, rpcErr.Code)
+	}
+
+	// 不再接受新的连接
+	if _, err := net.Dial("unix", socketPath); err == nil {
+		t.Error("关闭过程中不应该接受新的连接")
+	}
+
+	select {
+	case err := <-shutdownResult:
+		t.Fatalf("Shutdown 应该等待处理中的请求, 提前返回: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-slowResult; err != nil {
+		t.Errorf("处理中的请求应该正常完成: %v", err)
+	}
+	if err := <-shutdownResult; err != nil {
+		t.Errorf("Shutdown 失败: %v", err)
+	}
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("socket 文件应该被删除: %v", err)
+	}
+
+	// 重复关闭是安全的
+	if err := server.Shutdown(ctx); err != nil {
+		t.Errorf("重复 Shutdown 失败: %v", err)
+	}
+	if err := server.Stop(); err != nil {
+		t.Errorf("重复 Stop 失败: %v", err)
+	}
+	if err := server.Start(); !errors.Is(err, ErrServerClosed) {
+		t.Errorf("关闭后启动应该返回 ErrServerClosed, 得到 %v", err)
+	}
+}
+
+// 测试优雅关闭 - 超过期限后直接断开连接
+func TestUnixServer_ShutdownTimeout(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "unix_server_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "test.sock")
+	server := NewUnixServer(socketPath)
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	server.RegisterHandler("stuck", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+
+	client, err := NewUnixClient(socketPath)
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	go client.Call(context.Background(), "stuck", nil)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := server.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("期望 DeadlineExceeded, 得到 %v", err)
+	}
+
+	// 断开连接后处理器的 ctx 被取消
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("处理器的 ctx 应该被取消")
+	}
+}
+
+// 测试 Start 与 Shutdown 并发 - Shutdown 开始后 Start 不会留下 listener 与 socket 文件
+func TestUnixServer_StartDuringShutdown(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "unix_server_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "test.sock")
+	server := NewUnixServer(socketPath).(*UnixServer)
+
+	// Shutdown 已经关闭了 listener，但还没有关闭 done
+	server.closeOnce.Do(func() {
+		_ = server.closeListener()
+	})
+	if err := server.Start(); !errors.Is(err, ErrServerClosed) {
+		t.Errorf("期望 ErrServerClosed, 得到 %v", err)
+	}
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("socket 文件应该被删除: %v", err)
+	}
+	if err := server.Stop(); err != nil {
+		t.Errorf("停止服务器失败: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		server := NewUnixServer(socketPath)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = server.Start()
+		}()
+		go func() {
+			defer wg.Done()
+			_ = server.Stop()
+		}()
+		wg.Wait()
+		if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+			t.Fatalf("socket 文件应该被删除: %v", err)
+		}
+	}
+}
+
+// 测试 Serve - ctx 结束或服务器被关闭时返回对应的错误
+func TestUnixServer_Serve(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "unix_server_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// ctx 结束
+	server := NewUnixServer(filepath.Join(tempDir, "cancel.sock"))
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- server.Serve(ctx)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("期望 context.Canceled, 得到 %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ctx 结束后 Serve 应该返回")
+	}
+
+	// 服务器被关闭
+	server = NewUnixServer(filepath.Join(tempDir, "shutdown.sock"))
+	go func() {
+		result <- server.Serve(context.Background())
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown 失败: %v", err)
+	}
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrServerClosed) {
+			t.Errorf("期望 ErrServerClosed, 得到 %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown 后 Serve 应该返回")
+	}
+}
+
+// failingListener 依次返回预设的错误
+type failingListener struct {
+	net.Listener
+	errs []error
+}
+
+func (l *failingListener) Accept() (net.Conn, error) {
+	err := l.errs[0]
+	if len(l.errs) > 1 {
+		l.errs = l.errs[1:]
+	}
+	return nil, err
+}
+
+func (l *failingListener) Close() error { return nil }
+
+// 测试接受连接失败 - 临时错误重试，其他错误停止服务器
+func TestUnixServer_AcceptError(t *testing.T) {
+	server := NewUnixServer(filepath.Join(os.TempDir(), "unused.sock")).(*UnixServer)
+	fatal := errors.New("listener broken")
+	listener := &failingListener{errs: []error{syscall.EMFILE, syscall.EMFILE, fatal}}
+
+	go server.acceptConnections(listener)
+
+	select {
+	case <-server.done:
+	case <-time.After(time.Second):
+		t.Fatal("发生无法恢复的错误后服务器应该停止")
+	}
+	if !errors.Is(server.err, fatal) {
+		t.Errorf("期望记录 %v, 得到 %v", fatal, server.err)
+	}
+}
@@ -0,0 +1,211 @@
+package gomcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Session 表示服务器与一个客户端之间的会话，每个连接对应一个会话。
+// 处理器可以通过 SessionFromContext 获取当前请求所属的会话，
+// 并通过 Set、Get 保存鉴权结果等连接级别的状态
+type Session struct {
+	id        string
+	createdAt time.Time
+	transport transport
+	calls     pendingCalls // 服务器发给客户端的请求，id 与客户端发来的请求相互独立
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu                 sync.RWMutex // 保护下面的字段
+	logLevel           LoggingLevel
+	roots              []Root
+	rootsCached        bool
+	rootsGeneration    uint64 // 每次收到 roots 变更通知时递增，避免缓存变更之前发出的请求的结果
+	protocolVersion    string
+	clientInfo         Implementation
+	clientCapabilities map[string]interface{}
+	serverCapabilities map[string]interface{} // initialize 时返回给客户端的能力
+	values             map[string]any
+}
+
+func newSession(t transport) *Session {
+	return &Session{
+		id:        newSessionID(),
+		createdAt: time.Now(),
+		transport: t,
+		done:      make(chan struct{}),
+	}
+}
+
+// newSessionID 生成随机的会话 id
+func newSessionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate session id: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// ID 返回会话的唯一 id
+func (s *Session) ID() string {
+	return s.id
+}
+
+// CreatedAt 返回会话建立的时间
+func (s *Session) CreatedAt() time.Time {
+	return s.createdAt
+}
+
+// ProtocolVersion 返回 initialize 时协商的协议版本，会话尚未初始化时为空
+func (s *Session) ProtocolVersion() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.protocolVersion
+}
+
+// ClientInfo 返回客户端在 initialize 时提供的名称与版本
+func (s *Session) ClientInfo() Implementation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.clientInfo
+}
+
+// ClientCapabilities 返回客户端在 initialize 时声明的能力的副本，会话尚未初始化时为 nil
+func (s *Session) ClientCapabilities() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.clientCapabilities == nil {
+		return nil
+	}
+	return copyJSONValue(s.clientCapabilities).(map[string]interface{})
+}
+
+// copyJSONValue 深拷贝由 JSON 解码得到的值，map 与切片都会复制
+func copyJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = copyJSONValue(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyJSONValue(item)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// Set 在会话中保存一个值，会话结束时随之释放
+func (s *Session) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = make(map[string]any)
+	}
+	s.values[key] = value
+}
+
+// Get 获取会话中保存的值
+func (s *Session) Get(key string) (any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// Delete 删除会话中保存的值
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+}
+
+type sessionContextKey struct{}
+
+// contextWithSession 将会话保存到 ctx 中
+func contextWithSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, session)
+}
+
+// SessionFromContext 获取 ctx 所属的会话，ctx 不属于任何会话时返回 nil
+func SessionFromContext(ctx context.Context) *Session {
+	session, _ := ctx.Value(sessionContextKey{}).(*Session)
+	return session
+}
+
+// Notify 向客户端发送一个通知
+func (s *Session) Notify(method string, params map[string]interface{}) error {
+	return s.write(context.Background(), Notification{
+		JsonRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// Call 向客户端发送请求并阻塞等待客户端的响应，返回响应中的 result；
+// 客户端返回错误时 err 为 *Error，会话结束时返回 ErrClosed
+func (s *Session) Call(ctx context.Context, method string, params map[string]interface{}) (json.RawMessage, error) {
+	request := &Request{
+		JsonRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	}
+	return s.calls.roundTrip(ctx, request, s.write, s.done, func() error { return ErrClosed })
+}
+
+// initialized 记录 initialize 时协商的协议版本、客户端信息与双方的能力
+func (s *Session) initialized(version string, clientInfo Implementation, clientCapabilities, serverCapabilities map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.protocolVersion = version
+	s.clientInfo = clientInfo
+	s.clientCapabilities = clientCapabilities
+	s.serverCapabilities = serverCapabilities
+}
+
+// listChangedEnabled 检查会话是否协商了指定列表的 listChanged 能力
+func (s *Session) listChangedEnabled(kind listKind) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	capability, _ := s.serverCapabilities[string(kind)].(map[string]interface{})
+	enabled, _ := capability["listChanged"].(bool)
+	return enabled
+}
+
+// Close 断开会话的连接，会话中正在处理的请求的 ctx 会被取消
+func (s *Session) Close() error {
+	s.close()
+	if err := s.transport.close(); err != nil && !isClosedError(err) {
+		return err
+	}
+	return nil
+}
+
+// close 结束会话，唤醒所有等待客户端响应的请求
+func (s *Session) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// write 向客户端写入一条消息，可以被并发调用
+func (s *Session) write(ctx context.Context, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	if err := s.transport.writeMessage(ctx, data); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
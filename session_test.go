@@ -0,0 +1,128 @@
+package gomcp
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 测试会话 - 记录客户端信息、保存连接级别的状态、列出与断开会话
+func TestSession_StateAndClose(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "session_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "test.sock")
+	server := NewUnixServer(socketPath)
+	server.RegisterHandler("login", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		SessionFromContext(ctx).Set("user", params["user"])
+		return nil, nil
+	})
+	server.RegisterHandler("whoami", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		session := SessionFromContext(ctx)
+		user, _ := session.Get("user")
+		return map[string]interface{}{
+			"id":      session.ID(),
+			"user":    user,
+			"client":  session.ClientInfo().Name,
+			"version": session.ProtocolVersion(),
+		}, nil
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	type whoami struct {
+		ID      string `json:"id"`
+		User    string `json:"user"`
+		Client  string `json:"client"`
+		Version string `json:"version"`
+	}
+	connect := func(user string) (Client, whoami) {
+		client, err := NewUnixClient(socketPath)
+		if err != nil {
+			t.Fatalf("创建客户端失败: %v", err)
+		}
+		_, err = client.Call(ctx, "initialize", map[string]interface{}{
+			"protocolVersion": "2025-03-26",
+			"clientInfo":      map[string]interface{}{"name": user + "-host", "version": "1.0"},
+			"capabilities":    map[string]interface{}{"roots": map[string]interface{}{}},
+		})
+		if err != nil {
+			t.Fatalf("初始化失败: %v", err)
+		}
+		if _, err := client.Call(ctx, "login", map[string]interface{}{"user": user}); err != nil {
+			t.Fatalf("调用失败: %v", err)
+		}
+		raw, err := client.Call(ctx, "whoami", nil)
+		if err != nil {
+			t.Fatalf("调用失败: %v", err)
+		}
+		var info whoami
+		if err := json.Unmarshal(raw, &info); err != nil {
+			t.Fatalf("解析结果失败: %v", err)
+		}
+		return client, info
+	}
+
+	alice, aliceInfo := connect("alice")
+	defer alice.Close()
+	bob, bobInfo := connect("bob")
+	defer bob.Close()
+
+	// 每个连接的状态相互独立
+	if aliceInfo.User != "alice" || aliceInfo.Client != "alice-host" || aliceInfo.Version != "2025-03-26" {
+		t.Errorf("会话信息错误: %+v", aliceInfo)
+	}
+	if bobInfo.User != "bob" || bobInfo.ID == aliceInfo.ID {
+		t.Errorf("会话信息错误: %+v", bobInfo)
+	}
+
+	sessions := server.Sessions()
+	if len(sessions) != 2 || sessions[0].ID() != aliceInfo.ID || sessions[1].ID() != bobInfo.ID {
+		t.Fatalf("会话列表错误: %v", sessions)
+	}
+	if _, ok := sessions[0].ClientCapabilities()["roots"]; !ok {
+		t.Errorf("客户端能力错误: %v", sessions[0].ClientCapabilities())
+	}
+	// 返回的是副本，修改不会影响会话的状态
+	capabilities := sessions[0].ClientCapabilities()
+	delete(capabilities, "roots")
+	if roots, ok := sessions[0].ClientCapabilities()["roots"].(map[string]interface{}); ok {
+		roots["listChanged"] = "changed"
+	}
+	if roots, ok := sessions[0].ClientCapabilities()["roots"].(map[string]interface{}); !ok || roots["listChanged"] == "changed" {
+		t.Errorf("修改返回的能力不应该影响会话: %v", sessions[0].ClientCapabilities())
+	}
+
+	// 断开 alice 的会话
+	if !server.CloseSession(aliceInfo.ID) {
+		t.Fatal("断开存在的会话应该返回 true")
+	}
+	if server.CloseSession("missing") {
+		t.Error("断开不存在的会话应该返回 false")
+	}
+	if _, err := alice.Call(ctx, "whoami", nil); err == nil {
+		t.Error("会话断开后调用应该失败")
+	}
+	if _, err := bob.Call(ctx, "whoami", nil); err != nil {
+		t.Errorf("其他会话不应该受影响: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(server.Sessions()) != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if sessions := server.Sessions(); len(sessions) != 1 || sessions[0].ID() != bobInfo.ID {
+		t.Errorf("会话列表错误: %v", sessions)
+	}
+}
@@ -0,0 +1,562 @@
+package gomcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ServerState 是受监管的服务器所处的状态
+type ServerState string
+
+const (
+	// ServerStarting 正在启动子进程或建立连接，并进行握手
+	ServerStarting ServerState = "starting"
+	// ServerReady 握手完成，可以处理请求
+	ServerReady ServerState = "ready"
+	// ServerCrashed 子进程退出、连接断开或启动失败
+	ServerCrashed ServerState = "crashed"
+	// ServerBackoff 等待下一次重启
+	ServerBackoff ServerState = "backoff"
+	// ServerFailed 连续失败次数超过上限，不再重启
+	ServerFailed ServerState = "failed"
+	// ServerStopped 服务器已被停止
+	ServerStopped ServerState = "stopped"
+)
+
+// defaultStartupTimeout 是服务器没有配置 startupTimeout 时，启动子进程或建立连接并完成握手的最长时间
+const defaultStartupTimeout = 30 * time.Second
+
+// errServerExited 子进程以退出码 0 退出，对于常驻的服务器同样视为崩溃
+var errServerExited = errors.New("server exited")
+
+// errServerDisconnected 远程服务器的连接断开
+var errServerDisconnected = errors.New("server disconnected")
+
+// NoRestart 作为 RestartPolicy.MaxRestarts 时，服务器崩溃后不再重启，直接进入 failed 状态
+const NoRestart = -1
+
+// RestartPolicy 服务器崩溃后的重启策略，零值字段使用默认值
+type RestartPolicy struct {
+	MaxRestarts int           // 连续重启的最大次数，超过后进入 failed 状态，默认为 5；为 NoRestart（任意负数）时不重启
+	Backoff     time.Duration // 第一次重启前的等待时间，之后每次翻倍，默认为 1s
+	MaxBackoff  time.Duration // 重启等待时间的上限，默认为 30s
+	ResetAfter  time.Duration // 服务器持续就绪超过该时间后重新计算连续重启次数，默认为 1min
+}
+
+func (p RestartPolicy) withDefaults() RestartPolicy {
+	if p.MaxRestarts == 0 {
+		p.MaxRestarts = 5
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = time.Second
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 30 * time.Second
+	}
+	if p.ResetAfter <= 0 {
+		p.ResetAfter = time.Minute
+	}
+	return p
+}
+
+// backoff 返回第 failures 次连续失败后的重启等待时间
+func (p RestartPolicy) backoff(failures int) time.Duration {
+	backoff := p.Backoff
+	for i := 1; i < failures && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, p.MaxBackoff)
+}
+
+// SupervisorEvent 是服务器状态变化的事件，Err 为进入 crashed、backoff 或 failed 状态的原因
+type SupervisorEvent struct {
+	Server   string
+	State    ServerState
+	Err      error
+	Restarts int
+	Time     time.Time
+}
+
+// ServerStatus 是受监管的服务器的当前状态
+type ServerStatus struct {
+	Name     string
+	State    ServerState
+	Err      error     // 最近一次失败的原因
+	Restarts int       // 已经重启的次数
+	Pid      int       // 子进程的进程号，未运行或不是 stdio 服务器时为 0
+	Since    time.Time // 进入当前状态的时间
+}
+
+// SupervisorOption 监管器的可选配置
+type SupervisorOption func(*supervisorOptions)
+
+type supervisorOptions struct {
+	policy        RestartPolicy
+	eventHandlers []func(SupervisorEvent)
+	logLines      int
+	clientOptions []ClientOption
+	logger        *slog.Logger
+}
+
+// WithRestartPolicy 设置服务器崩溃后的重启策略
+func WithRestartPolicy(policy RestartPolicy) SupervisorOption {
+	return func(o *supervisorOptions) {
+		o.policy = policy
+	}
+}
+
+// WithEventHandler 注册服务器状态变化的回调。回调在服务器的监管 goroutine 中同步调用，不应长时间阻塞
+func WithEventHandler(handler func(SupervisorEvent)) SupervisorOption {
+	return func(o *supervisorOptions) {
+		o.eventHandlers = append(o.eventHandlers, handler)
+	}
+}
+
+// WithLogLines 设置每个服务器保留的标准错误输出行数，默认为 1000
+func WithLogLines(lines int) SupervisorOption {
+	return func(o *supervisorOptions) {
+		o.logLines = lines
+	}
+}
+
+// WithSupervisorClientOptions 设置连接服务器时使用的客户端配置
+func WithSupervisorClientOptions(opts ...ClientOption) SupervisorOption {
+	return func(o *supervisorOptions) {
+		o.clientOptions = append(o.clientOptions, opts...)
+	}
+}
+
+// WithSupervisorLogger 设置监管器使用的 logger，未设置时使用 slog.Default()
+func WithSupervisorLogger(logger *slog.Logger) SupervisorOption {
+	return func(o *supervisorOptions) {
+		o.logger = logger
+	}
+}
+
+func (o *supervisorOptions) log() *slog.Logger {
+	if o.logger == nil {
+		return slog.Default()
+	}
+	return o.logger
+}
+
+// Supervisor 启动配置中所有启用的服务器，跟踪它们的状态，并在崩溃后按照重启策略重启
+type Supervisor struct {
+	opts    supervisorOptions
+	applyMu sync.Mutex // 串行执行 Apply
+
+	mu      sync.Mutex // 保护下面的字段
+	config  *Config
+	servers map[string]*supervisedServer
+	started bool
+}
+
+// supervisedServer 是一个受监管的服务器
+type supervisedServer struct {
+	name   string
+	logs   *LogBuffer
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.RWMutex // 保护下面的字段
+	status ServerStatus
+	client Client
+}
+
+// NewSupervisor 创建一个监管 config 中服务器的监管器
+func NewSupervisor(config *Config, opts ...SupervisorOption) *Supervisor {
+	o := supervisorOptions{logLines: 1000}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.policy = o.policy.withDefaults()
+	return &Supervisor{
+		config:  config,
+		opts:    o,
+		servers: make(map[string]*supervisedServer),
+	}
+}
+
+// Start 在后台启动配置中所有启用的服务器，不等待服务器就绪
+func (s *Supervisor) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return errors.New("supervisor already started")
+	}
+	s.started = true
+
+	names := make([]string, 0, len(s.config.MCPServers))
+	for name, config := range s.config.MCPServers {
+		if !config.Disabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s.startServer(name, nil)
+	}
+	return nil
+}
+
+// startServer 启动一个服务器的监管 goroutine，logs 为空时创建新的日志缓冲区。调用方需要持有 s.mu
+func (s *Supervisor) startServer(name string, logs *LogBuffer) {
+	if logs == nil {
+		logs = NewLogBuffer(s.opts.logLines)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	server := &supervisedServer{
+		name:   name,
+		logs:   logs,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		status: ServerStatus{Name: name},
+	}
+	s.servers[name] = server
+	go safe(s.opts.log(), func() {
+		s.supervise(ctx, server)
+	})()
+}
+
+// Stop 停止所有服务器并等待子进程退出，之后的 Apply 只替换配置，不再启动服务器
+func (s *Supervisor) Stop() error {
+	s.mu.Lock()
+	s.started = false
+	servers := make([]*supervisedServer, 0, len(s.servers))
+	for _, server := range s.servers {
+		servers = append(servers, server)
+	}
+	s.mu.Unlock()
+
+	stopServers(servers)
+	return nil
+}
+
+// stopServers 停止服务器并等待它们的监管 goroutine 结束
+func stopServers(servers []*supervisedServer) {
+	for _, server := range servers {
+		server.cancel()
+	}
+	for _, server := range servers {
+		<-server.done
+	}
+}
+
+// Apply 切换到新的配置，只调整受影响的服务器：停止被移除或禁用的服务器，启动新增的服务器，
+// 重启配置发生变化的服务器（保留其日志），其余服务器继续运行。监管器未启动时只替换配置
+func (s *Supervisor) Apply(config *Config) ConfigDiff {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+
+	s.mu.Lock()
+	diff := DiffConfig(s.config, config)
+	s.config = config
+	if !s.started {
+		s.mu.Unlock()
+		return diff
+	}
+	var stopping []*supervisedServer
+	logs := make(map[string]*LogBuffer)
+	for _, name := range append(diff.Removed, diff.Changed...) {
+		if server, exists := s.servers[name]; exists {
+			stopping = append(stopping, server)
+			logs[name] = server.logs
+			delete(s.servers, name)
+		}
+	}
+	s.mu.Unlock()
+
+	// 先等待旧的服务器退出，避免同一个服务器短暂地运行两个实例
+	stopServers(stopping)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started {
+		return diff // 等待期间监管器被停止
+	}
+	for _, name := range append(diff.Added, diff.Changed...) {
+		s.startServer(name, logs[name])
+	}
+	return diff
+}
+
+// currentConfig 返回当前的配置
+func (s *Supervisor) currentConfig() *Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config
+}
+
+// Status 返回指定服务器的状态
+func (s *Supervisor) Status(name string) (ServerStatus, bool) {
+	server, exists := s.server(name)
+	if !exists {
+		return ServerStatus{}, false
+	}
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+	return server.status, true
+}
+
+// Statuses 返回所有服务器的状态，按名称排序
+func (s *Supervisor) Statuses() []ServerStatus {
+	s.mu.Lock()
+	servers := make([]*supervisedServer, 0, len(s.servers))
+	for _, server := range s.servers {
+		servers = append(servers, server)
+	}
+	s.mu.Unlock()
+
+	statuses := make([]ServerStatus, 0, len(servers))
+	for _, server := range servers {
+		server.mu.RLock()
+		statuses = append(statuses, server.status)
+		server.mu.RUnlock()
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Client 返回已就绪服务器的客户端，服务器未就绪时返回 false。服务器重启后需要重新获取
+func (s *Supervisor) Client(name string) (Client, bool) {
+	server, exists := s.server(name)
+	if !exists {
+		return nil, false
+	}
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+	if server.client == nil {
+		return nil, false
+	}
+	return server.client, true
+}
+
+// Logs 返回服务器最近的标准错误输出，跨越重启保留
+func (s *Supervisor) Logs(name string) []string {
+	server, exists := s.server(name)
+	if !exists {
+		return nil
+	}
+	return server.logs.Lines()
+}
+
+func (s *Supervisor) server(name string) (*supervisedServer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	server, exists := s.servers[name]
+	return server, exists
+}
+
+// supervise 启动服务器并在崩溃后按照重启策略重启，直到 ctx 结束或失败次数超过上限
+func (s *Supervisor) supervise(ctx context.Context, server *supervisedServer) {
+	defer close(server.done)
+
+	policy := s.opts.policy
+	failures := 0
+	for {
+		s.setState(server, ServerStarting, nil, nil)
+		err := s.runOnce(ctx, server, &failures)
+		if ctx.Err() != nil {
+			s.setState(server, ServerStopped, nil, nil)
+			return
+		}
+
+		s.setState(server, ServerCrashed, err, nil)
+		failures++
+		if policy.MaxRestarts < 0 {
+			s.setState(server, ServerFailed, fmt.Errorf("restarts are disabled: %w", err), nil)
+			return
+		}
+		if failures > policy.MaxRestarts {
+			s.setState(server, ServerFailed, fmt.Errorf("gave up after %d consecutive restarts: %w", policy.MaxRestarts, err), nil)
+			return
+		}
+
+		s.setState(server, ServerBackoff, err, nil)
+		timer := time.NewTimer(policy.backoff(failures))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.setState(server, ServerStopped, nil, nil)
+			return
+		case <-timer.C:
+		}
+
+		server.mu.Lock()
+		server.status.Restarts++
+		server.mu.Unlock()
+	}
+}
+
+// runOnce 启动一次服务器并等待其退出，返回退出的原因。
+// 服务器持续就绪超过 ResetAfter 时将 failures 清零
+func (s *Supervisor) runOnce(ctx context.Context, server *supervisedServer, failures *int) error {
+	config := s.currentConfig()
+	timeout := defaultStartupTimeout
+	if startup := config.MCPServers[server.name].StartupTimeout; startup > 0 {
+		timeout = time.Duration(startup)
+	}
+	startCtx, cancel := context.WithTimeout(ctx, timeout)
+	client, err := config.connect(startCtx, server.name, server.logs, s.opts.clientOptions...)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	s.setState(server, ServerReady, nil, client)
+	readyAt := time.Now()
+	defer func() {
+		server.mu.Lock()
+		server.client = nil
+		server.status.Pid = 0
+		server.mu.Unlock()
+		_ = client.Close()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-clientDone(client):
+	}
+
+	if time.Since(readyAt) >= s.opts.policy.ResetAfter {
+		*failures = 0
+	}
+	process, isProcess := client.(*ProcessClient)
+	if !isProcess {
+		return errServerDisconnected
+	}
+	if err := process.ExitErr(); err != nil {
+		return fmt.Errorf("%w: %v", errServerExited, err)
+	}
+	return errServerExited
+}
+
+// clientDone 返回子进程退出或连接断开时关闭的 channel，客户端无法报告断开时返回 nil
+func clientDone(client Client) <-chan struct{} {
+	if process, ok := client.(*ProcessClient); ok {
+		return process.Exited()
+	}
+	if conn, ok := client.(interface{ Done() <-chan struct{} }); ok {
+		return conn.Done()
+	}
+	return nil
+}
+
+// setState 更新服务器状态并发出事件，client 不为空时记录就绪的客户端
+func (s *Supervisor) setState(server *supervisedServer, state ServerState, err error, client Client) {
+	now := time.Now()
+
+	server.mu.Lock()
+	server.status.State = state
+	server.status.Since = now
+	if err != nil {
+		server.status.Err = err
+	}
+	if client != nil {
+		server.client = client
+		if process, ok := client.(*ProcessClient); ok {
+			server.status.Pid = process.Pid()
+		}
+	}
+	restarts := server.status.Restarts
+	server.mu.Unlock()
+
+	attrs := []slog.Attr{slog.String("server", server.name), slog.String("state", string(state))}
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	s.opts.log().LogAttrs(context.Background(), level, "server state changed", attrs...)
+
+	event := SupervisorEvent{Server: server.name, State: state, Err: err, Restarts: restarts, Time: now}
+	for _, handler := range s.opts.eventHandlers {
+		handler(event)
+	}
+}
+
+// maxLogLineLength 是 LogBuffer 中一行的最大字节数，更长的输出会被拆分为多行
+const maxLogLineLength = 4096
+
+// LogBuffer 按行保存最近的输出，超过容量时丢弃最早的行，可以被并发使用
+type LogBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	next    int // lines 写满后下一行写入的位置
+	full    bool
+	partial strings.Builder // 尚未遇到换行的输出
+}
+
+// NewLogBuffer 创建一个最多保存 capacity 行的 LogBuffer
+func NewLogBuffer(capacity int) *LogBuffer {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &LogBuffer{lines: make([]string, 0, capacity)}
+}
+
+// Write 实现 io.Writer，按换行拆分输出
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data := string(p)
+	for {
+		i := strings.IndexByte(data, '\n')
+		if i < 0 {
+			b.writePartial(data)
+			return len(p), nil
+		}
+		b.writePartial(data[:i])
+		b.append(strings.TrimSuffix(b.partial.String(), "\r"))
+		b.partial.Reset()
+		data = data[i+1:]
+	}
+}
+
+// writePartial 保存尚未遇到换行的输出，超过 maxLogLineLength 时作为一行保存，
+// 避免进度条等不换行的输出占用无限的内存
+func (b *LogBuffer) writePartial(data string) {
+	for b.partial.Len()+len(data) > maxLogLineLength {
+		n := maxLogLineLength - b.partial.Len()
+		// 不在 UTF-8 字符的中间截断
+		for n > 0 && !utf8.RuneStart(data[n]) {
+			n--
+		}
+		if n == 0 && b.partial.Len() == 0 {
+			n = maxLogLineLength
+		}
+		b.partial.WriteString(data[:n])
+		b.append(b.partial.String())
+		b.partial.Reset()
+		data = data[n:]
+	}
+	b.partial.WriteString(data)
+}
+
+func (b *LogBuffer) append(line string) {
+	if !b.full {
+		b.lines = append(b.lines, line)
+		b.full = len(b.lines) == cap(b.lines)
+		return
+	}
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+}
+
+// Lines 按写入顺序返回保存的行，不包括尚未遇到换行的部分
+func (b *LogBuffer) Lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lines := make([]string, 0, len(b.lines))
+	lines = append(lines, b.lines[b.next:]...)
+	return append(lines, b.lines[:b.next]...)
+}
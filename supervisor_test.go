@@ -0,0 +1,219 @@
+package gomcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForState 等待服务器进入指定状态
+func waitForState(t *testing.T, supervisor *Supervisor, name string, state ServerState) ServerStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if status, _ := supervisor.Status(name); status.State == state {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	status, _ := supervisor.Status(name)
+	t.Fatalf("等待 %s 进入 %s 状态超时, 当前状态: %+v", name, state, status)
+	return status
+}
+
+// 测试监管器 - 服务器崩溃后重启并恢复就绪
+func TestSupervisor_Restart(t *testing.T) {
+	config := &Config{MCPServers: map[string]ServerConfig{
+		"helper":   helperServerConfig(""),
+		"disabled": {Command: "missing-command", Disabled: true},
+	}}
+
+	var (
+		mu     sync.Mutex
+		states []ServerState
+	)
+	supervisor := NewSupervisor(config,
+		WithRestartPolicy(RestartPolicy{Backoff: 10 * time.Millisecond}),
+		WithEventHandler(func(event SupervisorEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			states = append(states, event.State)
+		}),
+	)
+	if err := supervisor.Start(); err != nil {
+		t.Fatalf("启动监管器失败: %v", err)
+	}
+	defer supervisor.Stop()
+
+	status := waitForState(t, supervisor, "helper", ServerReady)
+	if status.Pid == 0 {
+		t.Error("就绪的服务器应该有进程号")
+	}
+	if _, exists := supervisor.Status("disabled"); exists {
+		t.Error("禁用的服务器不应该被启动")
+	}
+
+	// 让服务器崩溃
+	client, ok := supervisor.Client("helper")
+	if !ok {
+		t.Fatal("就绪的服务器应该有客户端")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	CallTool(ctx, client, "exit", nil)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, _ = supervisor.Status("helper")
+		if status.State == ServerReady && status.Restarts == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("服务器应该重启并恢复就绪, 当前状态: %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !errors.Is(status.Err, errServerExited) || !strings.Contains(status.Err.Error(), "exit status 3") {
+		t.Errorf("崩溃原因错误: %v", status.Err)
+	}
+
+	// 重启后的客户端可以正常使用
+	client, _ = supervisor.Client("helper")
+	if err := Ping(ctx, client); err != nil {
+		t.Errorf("ping 失败: %v", err)
+	}
+
+	if err := supervisor.Stop(); err != nil {
+		t.Errorf("停止监管器失败: %v", err)
+	}
+	if status, _ := supervisor.Status("helper"); status.State != ServerStopped || status.Pid != 0 {
+		t.Errorf("停止后的状态错误: %+v", status)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []ServerState{ServerStarting, ServerReady, ServerCrashed, ServerBackoff, ServerStarting, ServerReady, ServerStopped}
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("状态变化错误: 期望 %v, 得到 %v", expected, states)
+	}
+
+	logs := supervisor.Logs("helper")
+	if len(logs) != 2 || logs[0] != "helper started" || logs[1] != "helper started" {
+		t.Errorf("标准错误输出错误: %q", logs)
+	}
+}
+
+// 测试监管器 - 连续失败超过上限后不再重启
+func TestSupervisor_GiveUp(t *testing.T) {
+	config := &Config{MCPServers: map[string]ServerConfig{"fail": helperServerConfig("fail")}}
+	supervisor := NewSupervisor(config, WithRestartPolicy(RestartPolicy{MaxRestarts: 2, Backoff: 10 * time.Millisecond}))
+	if err := supervisor.Start(); err != nil {
+		t.Fatalf("启动监管器失败: %v", err)
+	}
+	defer supervisor.Stop()
+
+	status := waitForState(t, supervisor, "fail", ServerFailed)
+	if status.Restarts != 2 {
+		t.Errorf("重启次数错误: 期望 2, 得到 %d", status.Restarts)
+	}
+	if _, ok := supervisor.Client("fail"); ok {
+		t.Error("失败的服务器不应该有客户端")
+	}
+	if logs := supervisor.Logs("fail"); len(logs) != 6 || logs[5] != "helper failing" {
+		t.Errorf("标准错误输出错误: %q", logs)
+	}
+
+	// NoRestart 时第一次崩溃后直接进入 failed 状态
+	once := NewSupervisor(config, WithRestartPolicy(RestartPolicy{MaxRestarts: NoRestart, Backoff: 10 * time.Millisecond}))
+	if err := once.Start(); err != nil {
+		t.Fatalf("启动监管器失败: %v", err)
+	}
+	defer once.Stop()
+	status = waitForState(t, once, "fail", ServerFailed)
+	if status.Restarts != 0 || !strings.Contains(status.Err.Error(), "restarts are disabled") {
+		t.Errorf("不重启的服务器状态错误: %+v", status)
+	}
+	if logs := once.Logs("fail"); len(logs) != 2 {
+		t.Errorf("标准错误输出错误: %q", logs)
+	}
+}
+
+// 测试监管器 - 停止后应用新的配置只替换配置，不启动服务器
+func TestSupervisor_ApplyAfterStop(t *testing.T) {
+	config := &Config{MCPServers: map[string]ServerConfig{"helper": helperServerConfig("")}}
+	supervisor := NewSupervisor(config, WithRestartPolicy(RestartPolicy{Backoff: 10 * time.Millisecond}))
+	if err := supervisor.Start(); err != nil {
+		t.Fatalf("启动监管器失败: %v", err)
+	}
+	waitForState(t, supervisor, "helper", ServerReady)
+	if err := supervisor.Stop(); err != nil {
+		t.Fatalf("停止监管器失败: %v", err)
+	}
+
+	changed := helperServerConfig("")
+	changed.Env["GOMCP_HELPER_REVISION"] = "2"
+	updated := &Config{MCPServers: map[string]ServerConfig{
+		"helper": changed,
+		"added":  helperServerConfig(""),
+	}}
+	diff := supervisor.Apply(updated)
+	expected := ConfigDiff{Added: []string{"added"}, Changed: []string{"helper"}}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("配置差异错误: 期望 %+v, 得到 %+v", expected, diff)
+	}
+	if _, exists := supervisor.Status("added"); exists {
+		t.Error("停止后不应该启动新增的服务器")
+	}
+	if status, _ := supervisor.Status("helper"); status.State != ServerStopped {
+		t.Errorf("停止后不应该重启配置变化的服务器: %+v", status)
+	}
+	if supervisor.currentConfig() != updated {
+		t.Error("停止后应用的配置应该被保存")
+	}
+}
+
+// 测试重启等待时间
+func TestRestartPolicy_Backoff(t *testing.T) {
+	policy := RestartPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}.withDefaults()
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := policy.backoff(i + 1); got != want {
+			t.Errorf("第 %d 次失败的等待时间错误: 期望 %v, 得到 %v", i+1, want, got)
+		}
+	}
+}
+
+// 测试日志缓冲区 - 按行保存并丢弃最早的行
+func TestLogBuffer(t *testing.T) {
+	buffer := NewLogBuffer(3)
+	fmt.Fprint(buffer, "line 1\nline 2\r\nli")
+	fmt.Fprint(buffer, "ne 3\nline 4\nline 5\npartial")
+
+	expected := []string{"line 3", "line 4", "line 5"}
+	if lines := buffer.Lines(); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("缓冲区内容错误: 期望 %q, 得到 %q", expected, lines)
+	}
+
+	// 不换行的输出超过一行的上限时拆分为多行，不会无限占用内存
+	buffer = NewLogBuffer(3)
+	for i := 0; i < 1000; i++ {
+		fmt.Fprint(buffer, strings.Repeat("#", 100)+"\r")
+	}
+	lines := buffer.Lines()
+	if len(lines) != 3 {
+		t.Fatalf("缓冲区行数错误: 期望 3, 得到 %d", len(lines))
+	}
+	for _, line := range lines {
+		if len(line) != maxLogLineLength {
+			t.Errorf("拆分后的行长度错误: 期望 %d, 得到 %d", maxLogLineLength, len(line))
+		}
+	}
+	if buffer.partial.Len() > maxLogLineLength {
+		t.Errorf("未换行的输出超过上限: %d", buffer.partial.Len())
+	}
+}
@@ -0,0 +1,171 @@
+package gomcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Tool 描述服务器提供的一个工具，InputSchema 为参数的 JSON Schema，未设置时为任意对象
+type Tool struct {
+	Name         string                 `json:"name"`
+	Title        string                 `json:"title,omitempty"`
+	Description  string                 `json:"description,omitempty"`
+	InputSchema  map[string]interface{} `json:"inputSchema"`
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
+}
+
+// CallToolResult 是 tools/call 请求的结果，IsError 表示工具执行失败，错误信息放在 Content 中
+type CallToolResult struct {
+	Content           []Content              `json:"content"`
+	StructuredContent map[string]interface{} `json:"structuredContent,omitempty"`
+	IsError           bool                   `json:"isError,omitempty"`
+}
+
+// ToolHandler 执行工具调用。返回 *Error 时作为协议错误返回给客户端，
+// 返回其他错误时转换为 IsError 为 true 的结果，让模型能够看到失败原因
+type ToolHandler func(ctx context.Context, arguments map[string]interface{}) (*CallToolResult, error)
+
+// toolEntry 是已注册的工具及其处理器
+type toolEntry struct {
+	tool    Tool
+	handler ToolHandler
+}
+
+// registerTool 注册工具，同名工具会被替换，并通知已连接的客户端
+func (c *serverCore) registerTool(tool Tool, handler ToolHandler) {
+	if tool.InputSchema == nil {
+		tool.InputSchema = map[string]interface{}{"type": "object"}
+	}
+
+	c.mu.Lock()
+	if c.tools == nil {
+		c.tools = make(map[string]*toolEntry)
+	}
+	c.tools[tool.Name] = &toolEntry{tool: tool, handler: handler}
+	c.mu.Unlock()
+
+	c.notifyListChanged(listTools)
+}
+
+// removeTool 移除工具并通知已连接的客户端，工具不存在时返回 false
+func (c *serverCore) removeTool(name string) bool {
+	c.mu.Lock()
+	_, exists := c.tools[name]
+	delete(c.tools, name)
+	c.mu.Unlock()
+
+	if exists {
+		c.notifyListChanged(listTools)
+	}
+	return exists
+}
+
+// listTools 处理 tools/list 请求，按名称排序返回所有工具
+func (c *serverCore) listTools(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	c.mu.RLock()
+	tools := make([]Tool, 0, len(c.tools))
+	for _, entry := range c.tools {
+		tools = append(tools, entry.tool)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return map[string]interface{}{"tools": tools}, nil
+}
+
+// callTool 处理 tools/call 请求
+func (c *serverCore) callTool(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	name, _ := params["name"].(string)
+	arguments, _ := params["arguments"].(map[string]interface{})
+
+	c.mu.RLock()
+	entry, exists := c.tools[name]
+	c.mu.RUnlock()
+	if !exists {
+		return nil, NewError(InvalidParams, fmt.Sprintf("Tool not found: %s", name), nil)
+	}
+
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	result, err := entry.handler(ctx, arguments)
+	if err != nil {
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) {
+			return nil, rpcErr
+		}
+		return &CallToolResult{Content: []Content{TextContent(err.Error())}, IsError: true}, nil
+	}
+	if result == nil {
+		result = &CallToolResult{}
+	}
+	if result.Content == nil {
+		result.Content = []Content{}
+	}
+	return result, nil
+}
+
+// ListTools 获取服务器提供的所有工具，服务器分页返回时依次获取所有页
+func ListTools(ctx context.Context, client Client) ([]Tool, error) {
+	return listAll[Tool](ctx, client, "tools/list", "tools")
+}
+
+// listAll 调用 method 获取列表，按响应中的 nextCursor 依次请求后续的页，合并 key 对应的条目
+func listAll[T any](ctx context.Context, client Client, method, key string) ([]T, error) {
+	var items []T
+	cursors := make(map[string]bool)
+	var params map[string]interface{}
+	for {
+		raw, err := client.Call(ctx, method, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var result map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %w", key, err)
+		}
+		var page []T
+		if data, exists := result[key]; exists {
+			if err := json.Unmarshal(data, &page); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal %s: %w", key, err)
+			}
+		}
+		items = append(items, page...)
+
+		var cursor string
+		if data, exists := result["nextCursor"]; exists {
+			if err := json.Unmarshal(data, &cursor); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal %s cursor: %w", key, err)
+			}
+		}
+		if cursor == "" {
+			return items, nil
+		}
+		if cursors[cursor] {
+			return nil, fmt.Errorf("server repeated %s cursor %q", key, cursor)
+		}
+		cursors[cursor] = true
+		params = map[string]interface{}{"cursor": cursor}
+	}
+}
+
+// CallTool 调用服务器上的工具，工具执行失败时返回 IsError 为 true 的结果而不是错误
+func CallTool(ctx context.Context, client Client, name string, arguments map[string]interface{}) (*CallToolResult, error) {
+	raw, err := client.Call(ctx, "tools/call", map[string]interface{}{
+		"name":      name,
+		"arguments": arguments,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result CallToolResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tool result: %w", err)
+	}
+	return &result, nil
+}
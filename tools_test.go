@@ -0,0 +1,272 @@
+package gomcp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 测试工具 - 列出与调用工具
+func TestTools_ListAndCall(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tools_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "test.sock")
+	server := NewUnixServer(socketPath)
+	server.RegisterTool(Tool{Name: "echo"}, func(ctx context.Context, arguments map[string]interface{}) (*CallToolResult, error) {
+		text, _ := arguments["text"].(string)
+		return &CallToolResult{Content: []Content{TextContent(text)}}, nil
+	})
+	server.RegisterTool(Tool{Name: "fail"}, func(ctx context.Context, arguments map[string]interface{}) (*CallToolResult, error) {
+		if arguments["code"] != nil {
+			return nil, NewError(InvalidParams, "bad arguments", nil)
+		}
+		return nil, errors.New("disk full")
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	client, err := NewUnixClient(socketPath)
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tools, err := ListTools(ctx, client)
+	if err != nil {
+		t.Fatalf("获取工具列表失败: %v", err)
+	}
+	if len(tools) != 2 || tools[0].Name != "echo" || tools[1].Name != "fail" {
+		t.Fatalf("工具列表错误: %+v", tools)
+	}
+	if tools[0].InputSchema["type"] != "object" {
+		t.Errorf("默认 inputSchema 错误: %v", tools[0].InputSchema)
+	}
+
+	result, err := CallTool(ctx, client, "echo", map[string]interface{}{"text": "hello"})
+	if err != nil {
+		t.Fatalf("调用工具失败: %v", err)
+	}
+	if result.IsError || len(result.Content) != 1 || result.Content[0].Text != "hello" {
+		t.Errorf("工具结果错误: %+v", result)
+	}
+
+	// 普通错误作为工具结果返回
+	result, err = CallTool(ctx, client, "fail", nil)
+	if err != nil {
+		t.Fatalf("调用工具失败: %v", err)
+	}
+	if !result.IsError || result.Content[0].Text != "disk full" {
+		t.Errorf("工具结果错误: %+v", result)
+	}
+
+	// 处理器返回 (nil, nil) 时返回空的结果
+	server.RegisterTool(Tool{Name: "empty"}, func(ctx context.Context, arguments map[string]interface{}) (*CallToolResult, error) {
+		return nil, nil
+	})
+	result, err = CallTool(ctx, client, "empty", nil)
+	if err != nil {
+		t.Fatalf("调用工具失败: %v", err)
+	}
+	if result.IsError || result.Content == nil || len(result.Content) != 0 {
+		t.Errorf("工具结果错误: %+v", result)
+	}
+
+	// 提示词与资源的处理器返回 (nil, nil) 时同样返回必填字段为空列表的结果
+	server.RegisterPrompt(Prompt{Name: "empty"}, func(ctx context.Context, arguments map[string]string) (*GetPromptResult, error) {
+		return nil, nil
+	})
+	server.RegisterResource(Resource{URI: "file:///empty", Name: "empty"}, func(ctx context.Context, uri string) (*ReadResourceResult, error) {
+		return nil, nil
+	})
+	server.RegisterResourceTemplate(ResourceTemplate{URITemplate: "file:///empty/{name}", Name: "empty"}, func(ctx context.Context, uri string, variables map[string]string) (*ReadResourceResult, error) {
+		return nil, nil
+	})
+	for _, tt := range []struct {
+		method   string
+		params   map[string]interface{}
+		expected string
+	}{
+		{"prompts/get", map[string]interface{}{"name": "empty"}, `{"messages":[]}`},
+		{"resources/read", map[string]interface{}{"uri": "file:///empty"}, `{"contents":[]}`},
+		{"resources/read", map[string]interface{}{"uri": "file:///empty/x"}, `{"contents":[]}`},
+	} {
+		raw, err := client.Call(ctx, tt.method, tt.params)
+		if err != nil {
+			t.Fatalf("调用 %s 失败: %v", tt.method, err)
+		}
+		if string(raw) != tt.expected {
+			t.Errorf("%s 结果错误: 期望 %s, 得到 %s", tt.method, tt.expected, raw)
+		}
+	}
+
+	// *Error 与不存在的工具作为协议错误返回
+	var rpcErr *Error
+	_, err = CallTool(ctx, client, "fail", map[string]interface{}{"code": 1})
+	if !errors.As(err, &rpcErr) || rpcErr.Code != InvalidParams {
+		t.Errorf("期望 InvalidParams 错误, 得到 %v", err)
+	}
+	_, err = CallTool(ctx, client, "missing", nil)
+	if !errors.As(err, &rpcErr) || rpcErr.Code != InvalidParams {
+		t.Errorf("期望 InvalidParams 错误, 得到 %v", err)
+	}
+}
+
+// 测试列表变更通知 - 运行时增删工具、提示词与资源时通知已初始化的客户端
+func TestListChanged_Notifications(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tools_test")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socketPath := filepath.Join(tempDir, "test.sock")
+	server := NewUnixServer(socketPath)
+	if err := server.Start(); err != nil {
+		t.Fatalf("启动服务器失败: %v", err)
+	}
+	defer server.Stop()
+
+	newClient := func(changes chan<- string) Client {
+		var opts []ClientOption
+		for _, method := range []string{
+			"notifications/tools/list_changed",
+			"notifications/prompts/list_changed",
+			"notifications/resources/list_changed",
+		} {
+			opts = append(opts, WithNotificationHandler(method, func(method string, params map[string]interface{}) {
+				changes <- method
+			}))
+		}
+		client, err := NewUnixClient(socketPath, opts...)
+		if err != nil {
+			t.Fatalf("创建客户端失败: %v", err)
+		}
+		return client
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	changes := make(chan string, 10)
+	client := newClient(changes)
+	defer client.Close()
+	if _, err := client.Call(ctx, "initialize", map[string]interface{}{"protocolVersion": LatestProtocolVersion}); err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+
+	// 未初始化的客户端不会收到通知
+	uninitializedChanges := make(chan string, 10)
+	uninitialized := newClient(uninitializedChanges)
+	defer uninitialized.Close()
+	if err := Ping(ctx, uninitialized); err != nil {
+		t.Fatalf("ping 失败: %v", err)
+	}
+
+	expect := func(method string) {
+		t.Helper()
+		select {
+		case got := <-changes:
+			if got != method {
+				t.Errorf("通知错误: 期望 %s, 得到 %s", method, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("等待 %s 超时", method)
+		}
+	}
+
+	server.RegisterTool(Tool{Name: "echo"}, func(ctx context.Context, arguments map[string]interface{}) (*CallToolResult, error) {
+		return &CallToolResult{}, nil
+	})
+	expect("notifications/tools/list_changed")
+
+	server.RegisterPrompt(Prompt{Name: "greet"}, func(ctx context.Context, arguments map[string]string) (*GetPromptResult, error) {
+		return &GetPromptResult{}, nil
+	})
+	expect("notifications/prompts/list_changed")
+
+	server.RegisterResource(Resource{URI: "file:///readme.md", Name: "readme"}, func(ctx context.Context, uri string) (*ReadResourceResult, error) {
+		return &ReadResourceResult{Contents: []ResourceContents{{URI: uri, Text: "# readme"}}}, nil
+	})
+	expect("notifications/resources/list_changed")
+
+	raw, err := client.Call(ctx, "resources/read", map[string]interface{}{"uri": "file:///readme.md"})
+	if err != nil {
+		t.Fatalf("读取资源失败: %v", err)
+	}
+	if string(raw) != `{"contents":[{"uri":"file:///readme.md","text":"# readme"}]}` {
+		t.Errorf("资源内容错误: %s", raw)
+	}
+
+	if !server.RemoveTool("echo") {
+		t.Error("移除已注册的工具应该返回 true")
+	}
+	expect("notifications/tools/list_changed")
+
+	// 移除不存在的工具不发送通知
+	if server.RemoveTool("echo") {
+		t.Error("移除不存在的工具应该返回 false")
+	}
+	if !server.RemoveResource("file:///readme.md") {
+		t.Error("移除已注册的资源应该返回 true")
+	}
+	expect("notifications/resources/list_changed")
+
+	tools, err := ListTools(ctx, client)
+	if err != nil {
+		t.Fatalf("获取工具列表失败: %v", err)
+	}
+	if len(tools) != 0 {
+		t.Errorf("工具列表应该为空: %+v", tools)
+	}
+
+	// 替换列表方法的处理器同样发送通知，替换其他方法的处理器不发送
+	server.RegisterHandler("custom", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	server.RegisterHandler("prompts/list", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{"prompts": []Prompt{}}, nil
+	})
+	expect("notifications/prompts/list_changed")
+	if !server.RemoveHandler("prompts/list") {
+		t.Error("移除已注册的处理器应该返回 true")
+	}
+	expect("notifications/prompts/list_changed")
+
+	select {
+	case method := <-uninitializedChanges:
+		t.Errorf("未初始化的客户端不应该收到通知: %s", method)
+	default:
+	}
+}
+
+// 测试移除处理器
+func TestRemoveHandler(t *testing.T) {
+	server := NewStdioServer(nil, nil)
+	server.RegisterHandler("test", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+
+	if !server.RemoveHandler("test") {
+		t.Error("移除已注册的处理器应该返回 true")
+	}
+	if server.RemoveHandler("test") {
+		t.Error("移除不存在的处理器应该返回 false")
+	}
+
+	response := server.handleRequest(Request{JsonRPC: "2.0", Method: "test", ID: 1})
+	if response.Error == nil || response.Error.Code != MethodNotFound {
+		t.Errorf("期望 MethodNotFound 错误, 得到 %+v", response.Error)
+	}
+}
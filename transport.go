@@ -0,0 +1,60 @@
+package gomcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// transport 是底层的消息通道，每次读写一条完整的 JSON-RPC 消息
+type transport interface {
+	// readMessage 读取一条消息，连接关闭时返回 io.EOF
+	readMessage() ([]byte, error)
+	// writeMessage 写入一条消息，可以被并发调用。ctx 结束时放弃写入，
+	// 基于字节流的消息通道写入很快，只在写入前检查 ctx
+	writeMessage(ctx context.Context, data []byte) error
+	// close 关闭消息通道
+	close() error
+}
+
+// streamTransport 基于字节流的消息通道，消息之间以换行分隔
+type streamTransport struct {
+	decoder *json.Decoder
+	writer  io.Writer
+	closer  func() error
+	mu      sync.Mutex // 保护 writer 的并发写入
+}
+
+func newStreamTransport(reader io.Reader, writer io.Writer, closer func() error) *streamTransport {
+	return &streamTransport{
+		decoder: json.NewDecoder(reader),
+		writer:  writer,
+		closer:  closer,
+	}
+}
+
+func (t *streamTransport) readMessage() ([]byte, error) {
+	var raw json.RawMessage
+	if err := t.decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func (t *streamTransport) writeMessage(ctx context.Context, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := t.writer.Write(append(data, '\n'))
+	return err
+}
+
+func (t *streamTransport) close() error {
+	if t.closer == nil {
+		return nil
+	}
+	return t.closer()
+}
@@ -1,19 +1,33 @@
 package gomcp
 
 import (
-	"log"
+	"encoding/json"
+	"log/slog"
 	"runtime/debug"
 )
 
 // Safe mode, recover the panic, prevent crash the server.
 func Safe(f func()) func() {
+	return safe(slog.Default(), f)
+}
+
+// safe 与 Safe 相同，但通过指定的 logger 记录 panic
+func safe(logger *slog.Logger, f func()) func() {
 	return func() {
 		defer func() {
 			if rec := recover(); rec != nil {
-				stack := debug.Stack()
-				log.Printf("panic: %v, stack: %s", rec, stack)
+				logger.Error("goroutine panic", slog.Any("panic", rec), slog.String("stack", string(debug.Stack())))
 			}
 		}()
 		f()
 	}
 }
+
+// remarshal 通过 JSON 编解码将 in 转换为 out 的类型
+func remarshal(in any, out any) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}